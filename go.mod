@@ -1,6 +1,6 @@
 module github.com/KanShiori/kit
 
-go 1.20
+go 1.21
 
 require (
 	github.com/onsi/gomega v1.27.5
@@ -0,0 +1,330 @@
+// Package admin 提供用于查看与控制 runner.Registry 中 Runner 的 HTTP 接口.
+//
+// 路由(相对于挂载的路径):
+//
+//	GET  /                         HTML 页面
+//	GET  /runners                  所有 Runner 的 JSON 列表
+//	GET  /runners/{name}           单个 Runner 的 JSON
+//	POST /runners/{name}/{action}  执行 start, stop, pause, resume, run-now
+//
+// name 为 Registry.Snapshot 中的名称, 可以包含 namespace.
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/KanShiori/kit/runner"
+)
+
+var (
+	// ErrNotFound 表示不存在对应名称的 Runner
+	ErrNotFound = errors.New("runner not found")
+
+	// ErrNotSupported 表示 Runner 不支持对应的 action
+	ErrNotSupported = errors.New("action not supported by runner")
+)
+
+// RunnerInfo 为 Runner 的状态信息, 不支持的字段为零值
+type RunnerInfo struct {
+	Name           string    `json:"name"`
+	State          string    `json:"state"`
+	Interval       string    `json:"interval"`
+	Timeout        string    `json:"timeout"`
+	LastHandleTime time.Time `json:"last_handle_time"`
+	LastError      string    `json:"last_error,omitempty"`
+	Panics         uint64    `json:"panics"`
+	PausedFor      string    `json:"paused_for,omitempty"`
+}
+
+// Handler 为 admin 的 http.Handler.
+//
+// 通过 admin 停止的 Runner 会从 Registry 中移除, Handler 会继续记录它们, 以便查看与再次启动.
+// panic 等原因进入 StateFailed 的 Runner 会保留在 Registry 中, 因此同样可以查看与再次启动
+type Handler struct {
+	registry *runner.Registry
+
+	// StopTimeout 为 stop 等待 Runner 退出的时间上限, 为 0 时只受请求的 ctx 限制.
+	// 只对实现了 StopContext 的 Runner 生效, 超时时返回 504
+	StopTimeout time.Duration
+
+	mutex   sync.Mutex
+	stopped map[string]stoppedRunner // name : 通过 admin 停止的 Runner
+}
+
+type stoppedRunner struct {
+	registry *runner.Registry // Runner 所在 namespace 的 Registry
+	runner   runner.IRunner
+}
+
+// NewHandler 创建查看与控制 reg 中 Runner 的 Handler
+func NewHandler(reg *runner.Registry) *Handler {
+	return &Handler{
+		registry:    reg,
+		StopTimeout: 30 * time.Second,
+		stopped:     make(map[string]stoppedRunner),
+	}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	path := strings.Trim(req.URL.Path, "/")
+
+	switch {
+	case path == "":
+		if req.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", req.Method))
+			return
+		}
+		h.serveHTML(w)
+
+	case path == "runners":
+		if req.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", req.Method))
+			return
+		}
+		writeJSON(w, http.StatusOK, h.List())
+
+	case strings.HasPrefix(path, "runners/"):
+		name := strings.TrimPrefix(path, "runners/")
+		switch req.Method {
+		case http.MethodGet:
+			info, err := h.Info(name)
+			if err != nil {
+				writeError(w, http.StatusNotFound, err)
+				return
+			}
+			writeJSON(w, http.StatusOK, info)
+
+		case http.MethodPost:
+			i := strings.LastIndex(name, "/")
+			if i < 0 {
+				writeError(w, http.StatusNotFound, fmt.Errorf("missing action"))
+				return
+			}
+			h.serveAction(w, req, name[:i], name[i+1:])
+
+		default:
+			writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", req.Method))
+		}
+
+	default:
+		http.NotFound(w, req)
+	}
+}
+
+// List 返回所有 Runner 的信息, 按名称排序
+func (h *Handler) List() []RunnerInfo {
+	runners := h.registry.Snapshot()
+
+	h.mutex.Lock()
+	for name, s := range h.stopped {
+		if _, ok := runners[name]; !ok {
+			runners[name] = s.runner
+		}
+	}
+	h.mutex.Unlock()
+
+	names := make([]string, 0, len(runners))
+	for name := range runners {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	infos := make([]RunnerInfo, 0, len(names))
+	for _, name := range names {
+		infos = append(infos, info(name, runners[name]))
+	}
+	return infos
+}
+
+// Info 返回名为 name 的 Runner 的信息
+func (h *Handler) Info(name string) (RunnerInfo, error) {
+	_, r, err := h.lookup(name)
+	if err != nil {
+		return RunnerInfo{}, err
+	}
+	return info(name, r), nil
+}
+
+// Do 对名为 name 的 Runner 执行 action
+func (h *Handler) Do(name, action string) error {
+	return h.DoContext(context.Background(), name, action)
+}
+
+// DoContext 与 Do 相同, 但 stop 最多等待到 ctx 结束或 StopTimeout, 超时时返回 ctx 的 error
+func (h *Handler) DoContext(ctx context.Context, name, action string) error {
+	reg, r, err := h.lookup(name)
+	if err != nil {
+		return err
+	}
+
+	switch action {
+	case "start":
+		if err := startIn(reg, r); err != nil {
+			return err
+		}
+		h.mutex.Lock()
+		delete(h.stopped, name)
+		h.mutex.Unlock()
+		return nil
+
+	case "stop":
+		h.mutex.Lock()
+		h.stopped[name] = stoppedRunner{registry: reg, runner: r}
+		h.mutex.Unlock()
+		return h.stop(ctx, r)
+
+	case "pause":
+		p, ok := r.(interface{ Pause() })
+		if !ok {
+			return ErrNotSupported
+		}
+		p.Pause()
+		return nil
+
+	case "resume":
+		p, ok := r.(interface{ Resume() })
+		if !ok {
+			return ErrNotSupported
+		}
+		p.Resume()
+		return nil
+
+	case "run-now":
+		k, ok := r.(interface{ Kick() })
+		if !ok {
+			return ErrNotSupported
+		}
+		k.Kick()
+		return nil
+
+	default:
+		return fmt.Errorf("unknown action %s", action)
+	}
+}
+
+// stop 停止 r, 实现了 StopContext 的 Runner 最多等待到 ctx 结束或 StopTimeout
+func (h *Handler) stop(ctx context.Context, r runner.IRunner) error {
+	s, ok := r.(interface {
+		StopContext(ctx context.Context) error
+	})
+	if !ok {
+		r.Stop()
+		return nil
+	}
+
+	if h.StopTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.StopTimeout)
+		defer cancel()
+	}
+	return s.StopContext(ctx)
+}
+
+func (h *Handler) serveAction(w http.ResponseWriter, req *http.Request, name, action string) {
+	err := h.DoContext(req.Context(), name, action)
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled):
+		writeError(w, http.StatusGatewayTimeout, err)
+		return
+	case errors.Is(err, ErrNotFound):
+		writeError(w, http.StatusNotFound, err)
+		return
+	case errors.Is(err, ErrNotSupported):
+		writeError(w, http.StatusNotImplemented, err)
+		return
+	case err != nil:
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+
+	// 来自 HTML 页面的表单提交, 返回原页面
+	if referer := req.Referer(); referer != "" && strings.Contains(req.Header.Get("Accept"), "text/html") {
+		http.Redirect(w, req, referer, http.StatusSeeOther)
+		return
+	}
+
+	info, err := h.Info(name)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, info)
+}
+
+// lookup 在 Registry 与通过 admin 停止的 Runner 中查找名为 name 的 Runner
+func (h *Handler) lookup(name string) (*runner.Registry, runner.IRunner, error) {
+	if reg, r, ok := h.registry.Resolve(name); ok {
+		return reg, r, nil
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if s, ok := h.stopped[name]; ok {
+		return s.registry, s.runner, nil
+	}
+	return nil, nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+}
+
+func startIn(reg *runner.Registry, r runner.IRunner) error {
+	if s, ok := r.(interface {
+		StartIn(reg *runner.Registry) error
+	}); ok {
+		return s.StartIn(reg)
+	}
+	return r.Start()
+}
+
+func info(name string, r runner.IRunner) RunnerInfo {
+	info := RunnerInfo{
+		Name: name,
+	}
+
+	if s, ok := r.(interface{ State() runner.State }); ok {
+		info.State = s.State().String()
+	}
+	if i, ok := r.(interface{ Interval() time.Duration }); ok {
+		info.Interval = i.Interval().String()
+	}
+	if t, ok := r.(interface{ Timeout() time.Duration }); ok {
+		info.Timeout = t.Timeout().String()
+	}
+	if l, ok := r.(interface{ LastHandleTime() time.Time }); ok {
+		info.LastHandleTime = l.LastHandleTime()
+	}
+	if l, ok := r.(interface{ LastError() error }); ok {
+		if err := l.LastError(); err != nil {
+			info.LastError = err.Error()
+		}
+	}
+	if m, ok := r.(interface{ Metrics() *runner.Metrics }); ok {
+		info.Panics = m.Metrics().Snapshot().Panics
+	}
+	if p, ok := r.(interface {
+		PausedFor(curTime time.Time) time.Duration
+	}); ok {
+		if d := p.PausedFor(time.Now()); d > 0 {
+			info.PausedFor = d.Round(time.Millisecond).String()
+		}
+	}
+
+	return info
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
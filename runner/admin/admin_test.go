@@ -0,0 +1,158 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/KanShiori/kit/runner"
+)
+
+type testHandler struct{}
+
+func (h *testHandler) HandleContext(ctx context.Context) error {
+	return nil
+}
+
+func (h *testHandler) OnStart() error {
+	return nil
+}
+
+func (h *testHandler) OnExit() {
+}
+
+func do(h http.Handler, method, path string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+	return rec
+}
+
+func TestHandler(t *testing.T) {
+	req := require.New(t)
+
+	reg := runner.NewRegistry()
+	r := runner.NewContextRunner(&testHandler{}, "worker", time.Minute)
+	req.NoError(r.StartIn(reg.Namespace("ns")))
+	defer r.Stop()
+
+	h := NewHandler(reg)
+
+	rec := do(h, http.MethodGet, "/runners")
+	req.Equal(http.StatusOK, rec.Code)
+	var infos []RunnerInfo
+	req.NoError(json.Unmarshal(rec.Body.Bytes(), &infos))
+	req.Len(infos, 1)
+	req.Equal("ns/worker", infos[0].Name)
+	req.Equal("Running", infos[0].State)
+	req.Equal("1m0s", infos[0].Interval)
+
+	rec = do(h, http.MethodGet, "/")
+	req.Equal(http.StatusOK, rec.Code)
+	req.Contains(rec.Body.String(), `action="runners/ns/worker/stop"`)
+
+	// 停止后仍然可以查看与再次启动
+	rec = do(h, http.MethodPost, "/runners/ns/worker/stop")
+	req.Equal(http.StatusOK, rec.Code)
+	req.Equal(runner.StateStopped, r.State())
+	req.Equal(0, reg.Len())
+
+	rec = do(h, http.MethodGet, "/runners/ns/worker")
+	req.Equal(http.StatusOK, rec.Code)
+	req.True(strings.Contains(rec.Body.String(), `"state":"Stopped"`))
+
+	rec = do(h, http.MethodPost, "/runners/ns/worker/start")
+	req.Equal(http.StatusOK, rec.Code)
+	req.Equal(runner.StateRunning, r.State())
+	_, ok := reg.Namespace("ns").Lookup("worker")
+	req.True(ok)
+
+	rec = do(h, http.MethodPost, "/runners/ns/worker/start")
+	req.Equal(http.StatusConflict, rec.Code)
+
+	rec = do(h, http.MethodPost, "/runners/ns/worker/pause")
+	req.Equal(http.StatusOK, rec.Code)
+	req.Equal(runner.StatePaused, r.State())
+	time.Sleep(time.Millisecond)
+	info, err := h.Info("ns/worker")
+	req.NoError(err)
+	req.NotEmpty(info.PausedFor)
+
+	rec = do(h, http.MethodPost, "/runners/ns/worker/resume")
+	req.Equal(http.StatusOK, rec.Code)
+	req.Equal(runner.StateRunning, r.State())
+
+	rec = do(h, http.MethodPost, "/runners/missing/stop")
+	req.Equal(http.StatusNotFound, rec.Code)
+
+	rec = do(h, http.MethodDelete, "/runners/ns/worker")
+	req.Equal(http.StatusMethodNotAllowed, rec.Code)
+}
+
+func TestHandlerStopTimeout(t *testing.T) {
+	req := require.New(t)
+
+	// 忽略 ctx 的 Handle 会阻塞 stop
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	r := runner.Func("hung", time.Hour, func(ctx context.Context) error {
+		close(entered)
+		<-release
+		return nil
+	})
+	reg := runner.NewRegistry()
+	req.NoError(r.StartIn(reg))
+	<-entered
+
+	h := NewHandler(reg)
+	h.StopTimeout = 10 * time.Millisecond
+
+	rec := do(h, http.MethodPost, "/runners/hung/stop")
+	req.Equal(http.StatusGatewayTimeout, rec.Code)
+	req.Equal(runner.StateAbandoned, r.State())
+
+	// 放弃等待的 Runner 仍然可以查看
+	info, err := h.Info("hung")
+	req.NoError(err)
+	req.Equal("Abandoned", info.State)
+
+	close(release)
+	<-r.Done()
+	req.Equal(runner.StateStopped, r.State())
+}
+
+func TestHandlerFailedRunner(t *testing.T) {
+	req := require.New(t)
+
+	var calls int32
+	r := runner.Func("flaky", time.Millisecond, func(ctx context.Context) error {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			panic("boom")
+		}
+		return nil
+	})
+	reg := runner.NewRegistry()
+	req.NoError(r.StartIn(reg.Namespace("ns")))
+	defer r.Stop()
+	<-r.Done()
+
+	// 在 Handler 创建前 panic 的 Runner 仍然可以查看与再次启动
+	h := NewHandler(reg)
+	infos := h.List()
+	req.Len(infos, 1)
+	req.Equal("ns/flaky", infos[0].Name)
+	req.Equal("Failed", infos[0].State)
+	req.Equal(uint64(1), infos[0].Panics)
+
+	rec := do(h, http.MethodPost, "/runners/ns/flaky/start")
+	req.Equal(http.StatusOK, rec.Code)
+	req.Equal(runner.StateRunning, r.State())
+	_, ok := reg.Namespace("ns").Lookup("flaky")
+	req.True(ok)
+}
@@ -0,0 +1,57 @@
+package admin
+
+import (
+	"html/template"
+	"net/http"
+)
+
+// actions 为页面中每个 Runner 可执行的 action
+var actions = []string{"start", "stop", "pause", "resume", "run-now"}
+
+// pageTemplate 中的表单使用相对路径, 因此页面需要以 "/" 结尾的路径访问
+var pageTemplate = template.Must(template.New("runners").Funcs(template.FuncMap{
+	"actions": func() []string { return actions },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>runners</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; }
+form { display: inline; }
+</style>
+</head>
+<body>
+<h1>runners</h1>
+<table>
+<tr>
+<th>Name</th><th>State</th><th>Interval</th><th>Timeout</th><th>Last Handle</th><th>Last Error</th><th>Panics</th><th>Actions</th>
+</tr>
+{{- range .}}
+<tr>
+<td>{{.Name}}</td>
+<td>{{.State}}{{if .PausedFor}} ({{.PausedFor}}){{end}}</td>
+<td>{{.Interval}}</td>
+<td>{{.Timeout}}</td>
+<td>{{if not .LastHandleTime.IsZero}}{{.LastHandleTime.Format "2006-01-02 15:04:05"}}{{end}}</td>
+<td>{{.LastError}}</td>
+<td>{{.Panics}}</td>
+<td>
+{{- $name := .Name}}
+{{- range $action := actions}}
+<form method="post" action="runners/{{$name}}/{{$action}}"><button type="submit">{{$action}}</button></form>
+{{- end}}
+</td>
+</tr>
+{{- end}}
+</table>
+</body>
+</html>
+`))
+
+func (h *Handler) serveHTML(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_ = pageTemplate.Execute(w, h.List())
+}
@@ -0,0 +1,83 @@
+package runner
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Jitter 为退避时间的随机化方式, 用于避免多个 Runner 同时重试
+type Jitter int
+
+const (
+	// NoJitter 不进行随机化
+	NoJitter Jitter = iota
+	// FullJitter 在 [0, d) 中随机
+	FullJitter
+	// EqualJitter 在 [d/2, d) 中随机
+	EqualJitter
+)
+
+// Backoff 描述 Handle 连续失败时下一次执行前的等待时间.
+//
+// 第 n 次连续失败后等待 Initial * Multiplier^(n-1), 最大为 Max, 再按 Jitter 随机化.
+// Max 小于等于 0 时不限制. Multiplier 为 0 时为 2, 为 1 时不增长, 小于 1 时按 1 处理.
+// Handle 成功后连续失败次数清零, 恢复使用 Runner.Interval
+type Backoff struct {
+	Initial    time.Duration
+	Max        time.Duration
+	Multiplier float64
+	Jitter     Jitter
+}
+
+// NewBackoff 创建一个倍数为 2, 使用 EqualJitter 的 Backoff
+func NewBackoff(initial, max time.Duration) *Backoff {
+	return &Backoff{
+		Initial:    initial,
+		Max:        max,
+		Multiplier: 2,
+		Jitter:     EqualJitter,
+	}
+}
+
+// Duration 返回连续失败 failures 次后的等待时间
+func (b *Backoff) Duration(failures int) time.Duration {
+	if failures <= 0 {
+		return 0
+	}
+
+	max := float64(math.MaxInt64)
+	if b.Max > 0 {
+		max = float64(b.Max)
+	}
+	multiplier := b.Multiplier
+	switch {
+	case multiplier == 0:
+		multiplier = 2
+	case multiplier < 1:
+		// 小于 1 会使等待时间不断缩短, 按不增长处理
+		multiplier = 1
+	}
+
+	d := float64(b.Initial)
+	for i := 1; i < failures && d < max && multiplier > 1; i++ {
+		d *= multiplier
+	}
+	if d > max {
+		d = max
+	}
+
+	switch b.Jitter {
+	case FullJitter:
+		d = rand.Float64() * d
+	case EqualJitter:
+		d = d/2 + rand.Float64()*d/2
+	}
+
+	// float64(math.MaxInt64) 向上舍入为 2^63, 直接转换会溢出
+	if d >= float64(math.MaxInt64) {
+		return math.MaxInt64
+	}
+
+	return time.Duration(d)
+}
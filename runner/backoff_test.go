@@ -0,0 +1,94 @@
+package runner
+
+import (
+	"errors"
+	"math"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackoffDuration(t *testing.T) {
+	req := require.New(t)
+
+	b := &Backoff{
+		Initial:    10 * time.Millisecond,
+		Max:        100 * time.Millisecond,
+		Multiplier: 2,
+		Jitter:     NoJitter,
+	}
+	req.Equal(time.Duration(0), b.Duration(0))
+	req.Equal(10*time.Millisecond, b.Duration(1))
+	req.Equal(20*time.Millisecond, b.Duration(2))
+	req.Equal(80*time.Millisecond, b.Duration(4))
+	req.Equal(100*time.Millisecond, b.Duration(5))
+	req.Equal(100*time.Millisecond, b.Duration(1000))
+
+	// Max 为 0 时不限制
+	b.Max = 0
+	req.Equal(80*time.Millisecond, b.Duration(4))
+	req.Equal(time.Duration(math.MaxInt64), b.Duration(1000))
+
+	// Multiplier 未设置时为 2
+	b = &Backoff{Initial: 10 * time.Millisecond, Max: 100 * time.Millisecond}
+	req.Equal(10*time.Millisecond, b.Duration(1))
+	req.Equal(20*time.Millisecond, b.Duration(2))
+	req.Equal(100*time.Millisecond, b.Duration(10))
+
+	// Multiplier 为 1 时不增长, 小于 1 时按 1 处理
+	constant := &Backoff{Initial: time.Second, Multiplier: 1}
+	req.Equal(time.Second, constant.Duration(3))
+	req.Equal(time.Second, constant.Duration(1000))
+	constant.Multiplier = 0.5
+	req.Equal(time.Second, constant.Duration(3))
+
+	for i := 0; i < 100; i++ {
+		b.Jitter = FullJitter
+		d := b.Duration(3)
+		req.True(d >= 0 && d < 40*time.Millisecond, "full jitter %s", d)
+
+		b.Jitter = EqualJitter
+		d = b.Duration(3)
+		req.True(d >= 20*time.Millisecond && d < 40*time.Millisecond, "equal jitter %s", d)
+	}
+}
+
+type flakyHandler struct {
+	calls int32
+	fails int32
+}
+
+func (h *flakyHandler) Handle() error {
+	if atomic.AddInt32(&h.calls, 1) <= atomic.LoadInt32(&h.fails) {
+		return errors.New("upstream unavailable")
+	}
+	return nil
+}
+
+func (h *flakyHandler) OnStart() error {
+	return nil
+}
+
+func (h *flakyHandler) OnExit() {
+}
+
+func TestRunnerBackoff(t *testing.T) {
+	req := require.New(t)
+
+	h := &flakyHandler{fails: 3}
+	r := NewErrorRunner(h, "TestRunnerBackoff", time.Hour)
+	r.Backoff = NewBackoff(time.Millisecond, 5*time.Millisecond)
+	req.NoError(r.Start())
+	defer r.Stop()
+
+	// 失败期间按 Backoff 重试, 成功后清零并恢复使用 Interval
+	req.Eventually(func() bool {
+		return atomic.LoadInt32(&h.calls) == 4 && r.ConsecutiveFailures() == 0
+	}, time.Second, time.Millisecond)
+	req.EqualError(r.LastError(), "upstream unavailable")
+
+	time.Sleep(20 * time.Millisecond)
+	req.Equal(int32(4), atomic.LoadInt32(&h.calls))
+}
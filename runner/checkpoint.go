@@ -0,0 +1,139 @@
+package runner
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Checkpoint 为 Runner 持久化的执行记录, 用于进程重启后恢复调度
+type Checkpoint struct {
+	// LastStart 为上一次 Handle 开始的时间
+	LastStart time.Time `json:"last_start"`
+
+	// LastSuccess 为上一次 Handle 成功结束的时间
+	LastSuccess time.Time `json:"last_success"`
+
+	// LastError 为上一次 Handle 失败结束的时间, Error 为其返回的 error
+	LastError time.Time `json:"last_error"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// lastEnd 返回上一次 Handle 结束的时间
+func (c Checkpoint) lastEnd() time.Time {
+	if c.LastError.After(c.LastSuccess) {
+		return c.LastError
+	}
+	return c.LastSuccess
+}
+
+// CheckpointStore 按 Runner 名称保存 Checkpoint. 实现需要是并发安全的
+type CheckpointStore interface {
+	// Load 返回 name 的 Checkpoint, 不存在时 ok 为 false
+	Load(name string) (cp Checkpoint, ok bool, err error)
+
+	// Save 保存 name 的 Checkpoint
+	Save(name string, cp Checkpoint) error
+}
+
+// JSONFileStore 是将所有 Runner 的 Checkpoint 以 JSON 保存在同一个文件中的 CheckpointStore.
+//
+// 文件只在第一次 Load 或 Save 时读取, 之后使用内存中的记录. 每次 Save 都会重写整个文件,
+// 写入临时文件并 fsync 后 rename, 因此文件不会处于写了一半的状态.
+// 只保证同一进程内的并发安全, 多个进程不应使用同一个文件
+type JSONFileStore struct {
+	path string
+
+	mutex       sync.Mutex
+	checkpoints map[string]Checkpoint // 第一次读取文件后不为 nil
+}
+
+// NewJSONFileStore 创建保存到 path 的 JSONFileStore, 文件不存在时会在 Save 时创建
+func NewJSONFileStore(path string) *JSONFileStore {
+	return &JSONFileStore{
+		path: path,
+	}
+}
+
+func (s *JSONFileStore) Load(name string) (Checkpoint, bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := s.load(); err != nil {
+		return Checkpoint{}, false, err
+	}
+	cp, ok := s.checkpoints[name]
+	return cp, ok, nil
+}
+
+func (s *JSONFileStore) Save(name string, cp Checkpoint) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := s.load(); err != nil {
+		return err
+	}
+	s.checkpoints[name] = cp
+
+	data, err := json.MarshalIndent(s.checkpoints, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal checkpoints: %w", err)
+	}
+	return s.write(data)
+}
+
+// load 在第一次调用时读取文件中所有的 Checkpoint, 文件不存在时为空
+func (s *JSONFileStore) load() error {
+	if s.checkpoints != nil {
+		return nil
+	}
+	checkpoints := make(map[string]Checkpoint)
+
+	data, err := os.ReadFile(s.path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("read checkpoint file: %w", err)
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &checkpoints); err != nil {
+			return fmt.Errorf("unmarshal checkpoint file %s: %w", s.path, err)
+		}
+	}
+
+	s.checkpoints = checkpoints
+	return nil
+}
+
+// write 将 data 写入临时文件并 fsync 后 rename 为 path
+func (s *JSONFileStore) write(data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("create checkpoint file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("write checkpoint file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("sync checkpoint file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write checkpoint file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("rename checkpoint file: %w", err)
+	}
+
+	// fsync 所在目录使 rename 持久化, 部分平台不支持对目录 fsync, 因此忽略其错误
+	if dir, err := os.Open(filepath.Dir(s.path)); err == nil {
+		_ = dir.Sync()
+		_ = dir.Close()
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/KanShiori/kit/timer"
+)
+
+func TestJSONFileStore(t *testing.T) {
+	req := require.New(t)
+
+	path := filepath.Join(t.TempDir(), "checkpoints.json")
+	store := NewJSONFileStore(path)
+
+	_, ok, err := store.Load("a")
+	req.NoError(err)
+	req.False(ok)
+
+	now := time.Now().Truncate(time.Second)
+	req.NoError(store.Save("a", Checkpoint{LastStart: now, LastSuccess: now}))
+	req.NoError(store.Save("b", Checkpoint{LastStart: now, LastError: now, Error: "failed"}))
+
+	// 重新打开后仍然存在
+	cp, ok, err := NewJSONFileStore(path).Load("b")
+	req.NoError(err)
+	req.True(ok)
+	req.True(now.Equal(cp.LastError))
+	req.Equal("failed", cp.Error)
+
+	cp, ok, err = NewJSONFileStore(path).Load("a")
+	req.NoError(err)
+	req.True(ok)
+	req.True(now.Equal(cp.LastSuccess))
+
+	// 读取一次后使用内存中的记录, Save 不再读取文件
+	req.NoError(os.WriteFile(path, []byte("{"), 0o644))
+	req.NoError(store.Save("c", Checkpoint{LastStart: now}))
+	cp, ok, err = NewJSONFileStore(path).Load("a")
+	req.NoError(err)
+	req.True(ok)
+	req.True(now.Equal(cp.LastSuccess))
+}
+
+func TestCheckpointRestore(t *testing.T) {
+	req := require.New(t)
+
+	store := NewJSONFileStore(filepath.Join(t.TempDir(), "checkpoints.json"))
+	fail := true
+	newRunner := func() (*Runner, *testHandler) {
+		h := &testHandler{
+			handle: func(ctx context.Context) error {
+				if fail {
+					return errors.New("handle failed")
+				}
+				return nil
+			},
+		}
+		return NewContextRunner(h, "TestCheckpointRestore", time.Hour, WithCheckpoints(store), WithMaxRuns(1)), h
+	}
+
+	r, h := newRunner()
+	req.NoError(r.StartIn(NewRegistry()))
+	req.NoError(r.Wait())
+	req.Equal(1, h.count())
+
+	cp, ok, err := store.Load("TestCheckpointRestore")
+	req.NoError(err)
+	req.True(ok)
+	req.False(cp.LastStart.IsZero())
+	req.True(cp.LastSuccess.IsZero())
+	req.Equal("handle failed", cp.Error)
+
+	// 模拟进程重启: 上一次结束后不足 Interval, 不立即执行
+	fail = false
+	r, h = newRunner()
+	req.NoError(r.StartIn(NewRegistry()))
+	time.Sleep(20 * time.Millisecond)
+	req.Zero(h.count())
+	r.Stop()
+
+	// 距离上一次结束超过 Interval 时立即执行
+	cp.LastStart = cp.LastStart.Add(-2 * time.Hour)
+	cp.LastError = cp.LastError.Add(-2 * time.Hour)
+	req.NoError(store.Save("TestCheckpointRestore", cp))
+	r, h = newRunner()
+	req.NoError(r.StartIn(NewRegistry()))
+	req.NoError(r.Wait())
+	req.Equal(1, h.count())
+
+	cp, _, err = store.Load("TestCheckpointRestore")
+	req.NoError(err)
+	req.False(cp.LastSuccess.IsZero())
+}
+
+func TestCheckpointRestoreTimer(t *testing.T) {
+	req := require.New(t)
+
+	store := NewJSONFileStore(filepath.Join(t.TempDir(), "checkpoints.json"))
+	req.NoError(store.Save("TestCheckpointRestoreTimer", Checkpoint{
+		LastStart:   time.Now().Add(-time.Hour),
+		LastSuccess: time.Now().Add(-time.Hour),
+	}))
+
+	// 每天执行一次的任务在重启后不会重新执行
+	h := &testHandler{}
+	r := NewContextRunner(h, "TestCheckpointRestoreTimer", time.Millisecond,
+		WithCheckpoints(store),
+		WithTimer(timer.NewTimeSpan(24*time.Hour, time.Time{})),
+	)
+	req.NoError(r.StartIn(NewRegistry()))
+	time.Sleep(20 * time.Millisecond)
+	r.Stop()
+	req.Zero(h.count())
+}
+
+func TestResumeRateClock(t *testing.T) {
+	req := require.New(t)
+
+	last := time.Unix(1000, 0)
+	c := resumeRateClock(FixedRate{}, time.Minute, last)
+	req.Equal(last.Add(time.Minute), c.due())
+
+	c = resumeRateClock(FixedRate{Align: true}, time.Minute, time.Unix(960, 0))
+	req.Equal(time.Unix(1020, 0), c.due())
+}
+
+// slowHandler 为忽略 ctx 的 Handler, Handle 在 release 关闭后返回
+type slowHandler struct {
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (h *slowHandler) Handle() {
+	close(h.entered)
+	<-h.release
+}
+
+func (h *slowHandler) OnStart() error {
+	return nil
+}
+
+func (h *slowHandler) OnExit() {
+}
+
+func TestCheckpointSavedOnStop(t *testing.T) {
+	req := require.New(t)
+
+	store := NewJSONFileStore(filepath.Join(t.TempDir(), "checkpoints.json"))
+	h := &slowHandler{entered: make(chan struct{}), release: make(chan struct{})}
+	r := NewRunner(h, "TestCheckpointSavedOnStop", time.Hour, WithCheckpoints(store))
+	req.NoError(r.StartIn(NewRegistry()))
+	<-h.entered
+
+	// Stop 期间完成的 Handle 仍然保存, 重启后不会重新执行
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		close(h.release)
+	}()
+	r.Stop()
+
+	cp, ok, err := store.Load("TestCheckpointSavedOnStop")
+	req.NoError(err)
+	req.True(ok)
+	req.False(cp.LastSuccess.IsZero())
+}
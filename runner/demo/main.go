@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"time"
@@ -8,33 +9,14 @@ import (
 	"github.com/KanShiori/kit/runner"
 )
 
-type Demo struct {
-	*runner.Runner
-}
-
-func NewDemo() *Demo {
-	d := &Demo{}
-
-	d.Runner = runner.NewRunner(d, "Demo", time.Second)
-	return d
-}
-
-func (d *Demo) Handle() {
-	fmt.Println("is in handle")
-}
-
-func (d *Demo) OnStart() error {
-	return nil
-}
-
-func (d *Demo) OnExit() {
-}
-
 func main() {
-	d := NewDemo()
+	r := runner.Func("Demo", time.Second, func(ctx context.Context) error {
+		fmt.Println("is in handle")
+		return nil
+	}, runner.WithLogger(os.Stderr))
 
 	// - start runner
-	err := d.Start()
+	err := r.Start()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "start failed: %s", err.Error())
 		os.Exit(1)
@@ -44,5 +26,5 @@ func main() {
 	time.Sleep(10 * time.Second)
 
 	// - stop runner
-	d.Stop()
+	r.Stop()
 }
@@ -0,0 +1,88 @@
+package runner
+
+import (
+	"time"
+
+	"github.com/KanShiori/kit/eventbus"
+)
+
+// Runner 生命周期事件发布到 eventbus.Bus 的 topic, 对应的 Event.Data 为同名的 *Event 类型
+const (
+	TopicStarted      = "runner.started"
+	TopicStopped      = "runner.stopped"
+	TopicPanicked     = "runner.panicked"
+	TopicTimedOut     = "runner.timed_out"
+	TopicHandleFailed = "runner.handle_failed"
+)
+
+// StartedEvent 为 TopicStarted 的事件, Runner 启动成功后发布
+type StartedEvent struct {
+	Name string
+	Time time.Time
+
+	// Attempt 为 Runner 成功 Start 的次数, 从 1 开始
+	Attempt int
+}
+
+// StoppedEvent 为 TopicStopped 的事件, Runner 循环退出后发布
+type StoppedEvent struct {
+	Name string
+	Time time.Time
+
+	// Uptime 为本次运行的时长
+	Uptime time.Duration
+
+	// Err 为退出的原因, 正常停止或结束时为 nil
+	Err error
+}
+
+// PanickedEvent 为 TopicPanicked 的事件, Handle panic 后发布, 之后会发布 StoppedEvent.
+// 被 Recover 等 Middleware 恢复的 panic 同样会发布, 但 Runner 继续运行, 不会发布 StoppedEvent
+type PanickedEvent struct {
+	Name  string
+	Time  time.Time
+	Panic *PanicError
+}
+
+// TimedOutEvent 为 TopicTimedOut 的事件, Watchdog 检测到超时后发布到 Runner 的 Bus, 或由 EventAction 发布
+type TimedOutEvent struct {
+	Name string
+	Time time.Time
+
+	// HandleElapsed 为执行中的 Handle 已经执行的时间, 为 0 表示阻塞不在 Handle 中
+	HandleElapsed time.Duration
+}
+
+// HandleFailedEvent 为 TopicHandleFailed 的事件, Handle 返回 error 后发布
+type HandleFailedEvent struct {
+	Name string
+	Time time.Time
+
+	// Failures 为连续失败的次数
+	Failures int
+	Elapsed  time.Duration
+	Err      error
+}
+
+// EventAction 将 Watchdog 检测到的超时以 TimedOutEvent 发布到 bus.
+// 设置了 Bus 的 Runner 的超时会由 Watchdog 直接发布到其 Bus, EventAction 用于未设置 Bus 的 IRunner 或额外的 bus
+func EventAction(bus eventbus.Bus) WatchdogAction {
+	return func(report TimeoutReport) {
+		bus.Publish(TopicTimedOut, newTimedOutEvent(report))
+	}
+}
+
+func newTimedOutEvent(report TimeoutReport) *TimedOutEvent {
+	return &TimedOutEvent{
+		Name:          report.Name,
+		Time:          report.Time,
+		HandleElapsed: report.HandleElapsed,
+	}
+}
+
+// publish 在本次运行设置了 Bus 时发布事件
+func (r *Runner) publish(topic string, data interface{}) {
+	if settings := r.settings.Load(); settings != nil && settings.bus != nil {
+		settings.bus.Publish(topic, data)
+	}
+}
@@ -0,0 +1,136 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/KanShiori/kit/eventbus"
+)
+
+type eventRecorder struct {
+	name   string
+	events chan eventbus.Event
+}
+
+func (h *eventRecorder) EventHandle(e eventbus.Event) {
+	h.events <- e
+}
+
+func (h *eventRecorder) Name() string {
+	return h.name
+}
+
+// subscribeAll 订阅所有 Runner 事件, Bus 异步发布, 因此事件之间不保证顺序
+func subscribeAll(req *require.Assertions, bus eventbus.Bus) *eventRecorder {
+	h := &eventRecorder{name: "recorder", events: make(chan eventbus.Event, 100)}
+	for _, topic := range []string{TopicStarted, TopicStopped, TopicPanicked, TopicTimedOut, TopicHandleFailed} {
+		req.NoError(bus.Subscribe(topic, h))
+	}
+	return h
+}
+
+func (h *eventRecorder) wait(req *require.Assertions, n int) map[string]interface{} {
+	events := make(map[string]interface{})
+	for i := 0; i < n; i++ {
+		select {
+		case e := <-h.events:
+			events[e.Topic] = e.Data
+		case <-time.After(time.Second):
+			req.FailNow("event not published")
+		}
+	}
+	return events
+}
+
+func TestLifecycleEvents(t *testing.T) {
+	req := require.New(t)
+
+	bus := eventbus.NewEventBus()
+	h := subscribeAll(req, bus)
+
+	var runs int
+	r := Func("TestLifecycleEvents", time.Millisecond, func(ctx context.Context) error {
+		runs++
+		if runs == 1 {
+			return errors.New("handle failed")
+		}
+		panic("boom")
+	}, WithEventBus(bus))
+	req.NoError(r.StartIn(NewRegistry()))
+	req.Error(r.Wait())
+
+	events := h.wait(req, 4)
+
+	started := events[TopicStarted].(*StartedEvent)
+	req.Equal("TestLifecycleEvents", started.Name)
+	req.Equal(1, started.Attempt)
+
+	failed := events[TopicHandleFailed].(*HandleFailedEvent)
+	req.Equal(1, failed.Failures)
+	req.EqualError(failed.Err, "handle failed")
+
+	panicked := events[TopicPanicked].(*PanickedEvent)
+	req.Equal("boom", panicked.Panic.Value)
+
+	stopped := events[TopicStopped].(*StoppedEvent)
+	req.Equal(panicked.Panic, stopped.Err)
+}
+
+func TestEventAction(t *testing.T) {
+	req := require.New(t)
+
+	bus := eventbus.NewEventBus()
+	h := subscribeAll(req, bus)
+
+	EventAction(bus)(TimeoutReport{Name: "ns/worker", Time: time.Now(), HandleElapsed: time.Second})
+
+	event := h.wait(req, 1)[TopicTimedOut].(*TimedOutEvent)
+	req.Equal("ns/worker", event.Name)
+	req.Equal(time.Second, event.HandleElapsed)
+}
+
+func TestWatchdogTimedOutEvent(t *testing.T) {
+	req := require.New(t)
+
+	bus := eventbus.NewEventBus()
+	h := &eventRecorder{name: "recorder", events: make(chan eventbus.Event, 100)}
+	req.NoError(bus.Subscribe(TopicTimedOut, h))
+
+	reg := NewRegistry()
+	r := Func("TestWatchdogTimedOutEvent", time.Hour, func(ctx context.Context) error {
+		return nil
+	}, WithEventBus(bus))
+	req.NoError(r.StartIn(reg.Namespace("ns")))
+	defer r.Stop()
+
+	// 未安装 EventAction 时同样发布到 Runner 的 Bus
+	w := NewWatchdog(reg, time.Hour)
+	w.Check(time.Now().Add(2 * time.Hour))
+
+	event := h.wait(req, 1)[TopicTimedOut].(*TimedOutEvent)
+	req.Equal("ns/TestWatchdogTimedOutEvent", event.Name)
+}
+
+func TestRecoveredPanicEvent(t *testing.T) {
+	req := require.New(t)
+
+	bus := eventbus.NewEventBus()
+	h := &eventRecorder{name: "recorder", events: make(chan eventbus.Event, 100)}
+	req.NoError(bus.Subscribe(TopicPanicked, h))
+
+	r := Func("TestRecoveredPanicEvent", time.Hour, func(ctx context.Context) error {
+		panic("boom")
+	}, WithEventBus(bus), WithMiddleware(Recover()))
+	req.NoError(r.StartIn(NewRegistry()))
+	defer r.Stop()
+
+	// Recover 恢复的 panic 同样发布, Runner 继续运行
+	events := h.wait(req, 1)
+	perr := events[TopicPanicked].(*PanickedEvent).Panic
+	req.Equal("boom", perr.Value)
+	req.Equal(StateRunning, r.State())
+}
@@ -0,0 +1,233 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Group 按依赖关系有序地启动与停止一组 IRunner.
+//
+// Start 按拓扑顺序启动成员, 被依赖的成员先启动; 任一成员启动失败时, 按逆序停止已启动的成员.
+// Stop 按启动顺序的逆序停止成员, 整体不超过 StopTimeout.
+type Group struct {
+	name string
+
+	// Registry 为成员启动时使用的 Registry, 成员未实现 StartIn 时使用 Start
+	Registry *Registry
+
+	// StopTimeout 为 Stop 与 Run 停止所有成员的总时间上限, 为 0 时不限制
+	StopTimeout time.Duration
+
+	mutex   sync.Mutex
+	members []*member
+	started []*member // 按启动顺序记录已启动的成员, 运行中时不为 nil
+}
+
+type member struct {
+	runner    IRunner
+	dependsOn []string
+}
+
+// NewGroup 创建一个空的 Group
+func NewGroup(name string) *Group {
+	return &Group{
+		name:        name,
+		Registry:    DefaultRegistry,
+		StopTimeout: 30 * time.Second,
+	}
+}
+
+// Add 加入成员, dependsOn 为其依赖的其他成员的名称, 依赖会先于 runner 启动并后于 runner 停止
+func (g *Group) Add(runner IRunner, dependsOn ...string) error {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	for _, m := range g.members {
+		if m.runner.Name() == runner.Name() {
+			return fmt.Errorf("%w: %s", ErrDuplicateName, runner.Name())
+		}
+	}
+	g.members = append(g.members, &member{runner: runner, dependsOn: dependsOn})
+
+	return nil
+}
+
+// Start 按依赖顺序启动所有成员. 依赖不存在或存在循环依赖时不启动任何成员
+func (g *Group) Start() error {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if g.started != nil {
+		return ErrIsRunning
+	}
+
+	order, err := g.sort()
+	if err != nil {
+		return err
+	}
+
+	started := make([]*member, 0, len(order))
+	for _, m := range order {
+		if err := g.startMember(m); err != nil {
+			_, _ = stopMembers(context.Background(), started)
+			return fmt.Errorf("start runner %s failed {group=%s}: %w", m.runner.Name(), g.name, err)
+		}
+		started = append(started, m)
+	}
+	g.started = started
+
+	return nil
+}
+
+// Stop 按启动顺序的逆序停止所有成员, 超过 StopTimeout 时返回 context.DeadlineExceeded
+func (g *Group) Stop() error {
+	ctx := context.Background()
+	if g.StopTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, g.StopTimeout)
+		defer cancel()
+	}
+	return g.StopContext(ctx)
+}
+
+// StopContext 按启动顺序的逆序停止所有成员. 某个成员停止失败时仍会停止其余成员, 返回所有成员的 error;
+// ctx 结束时不再等待, 但仍会通知剩余的成员停止. 未能停止的成员会被保留, 可以再次调用 Stop 等待其停止
+func (g *Group) StopContext(ctx context.Context) error {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if g.started == nil {
+		return nil
+	}
+
+	remaining, err := stopMembers(ctx, g.started)
+	g.started = remaining
+	if err != nil {
+		return fmt.Errorf("stop group %s: %w", g.name, err)
+	}
+	return nil
+}
+
+// Run 启动所有成员并阻塞直到 ctx 结束, 然后停止所有成员
+func (g *Group) Run(ctx context.Context) error {
+	if err := g.Start(); err != nil {
+		return err
+	}
+
+	<-ctx.Done()
+
+	return g.Stop()
+}
+
+// RunUntilSignal 与 Run 相同, 但在收到 SIGINT 或 SIGTERM 时也会停止
+func (g *Group) RunUntilSignal(ctx context.Context) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	return g.Run(ctx)
+}
+
+func (g *Group) Name() string {
+	return g.name
+}
+
+func (g *Group) startMember(m *member) error {
+	if s, ok := m.runner.(interface{ StartIn(reg *Registry) error }); ok && g.Registry != nil {
+		return s.StartIn(g.Registry)
+	}
+	return m.runner.Start()
+}
+
+// sort 返回按依赖关系排序的成员, 同一层级内保持加入顺序
+func (g *Group) sort() ([]*member, error) {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	byName := make(map[string]*member, len(g.members))
+	for _, m := range g.members {
+		byName[m.runner.Name()] = m
+	}
+
+	marks := make(map[*member]int, len(g.members))
+	order := make([]*member, 0, len(g.members))
+
+	var visit func(m *member, path []string) error
+	visit = func(m *member, path []string) error {
+		path = append(path, m.runner.Name())
+
+		switch marks[m] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("dependency cycle %v {group=%s}", path, g.name)
+		}
+
+		marks[m] = visiting
+		for _, dep := range m.dependsOn {
+			d, ok := byName[dep]
+			if !ok {
+				return fmt.Errorf("runner %s depends on unknown runner %s {group=%s}", m.runner.Name(), dep, g.name)
+			}
+			if err := visit(d, path); err != nil {
+				return err
+			}
+		}
+		marks[m] = visited
+		order = append(order, m)
+
+		return nil
+	}
+
+	for _, m := range g.members {
+		if err := visit(m, nil); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// stopMembers 按逆序停止 members, 某个成员停止失败或 ctx 结束时仍继续停止剩余的成员.
+// ctx 结束后不再等待, 但仍会通知剩余的成员停止. 返回仍未停止的成员(按启动顺序)与所有成员的 error
+func stopMembers(ctx context.Context, members []*member) ([]*member, error) {
+	var remaining []*member
+	var errs []error
+	for i := len(members) - 1; i >= 0; i-- {
+		r := members[i].runner
+		if err := stopMember(ctx, r); err != nil {
+			remaining = append([]*member{members[i]}, remaining...)
+			errs = append(errs, fmt.Errorf("stop runner %s: %w", r.Name(), err))
+		}
+	}
+	return remaining, errors.Join(errs...)
+}
+
+// stopMember 停止 r, 最多等待到 ctx 结束
+func stopMember(ctx context.Context, r IRunner) error {
+	if s, ok := r.(interface {
+		StopContext(ctx context.Context) error
+	}); ok {
+		return s.StopContext(ctx)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		r.Stop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
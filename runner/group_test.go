@@ -0,0 +1,147 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// orderHandler 将 OnStart 与 OnExit 记录到共享的 events 中
+type orderHandler struct {
+	testHandler
+	name     string
+	startErr error
+
+	mutex  *sync.Mutex
+	events *[]string
+}
+
+func (h *orderHandler) OnStart() error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	*h.events = append(*h.events, "start "+h.name)
+	return h.startErr
+}
+
+func (h *orderHandler) OnExit() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	*h.events = append(*h.events, "exit "+h.name)
+}
+
+type groupRecorder struct {
+	mutex  sync.Mutex
+	events []string
+}
+
+func (rec *groupRecorder) runner(name string, startErr error) *Runner {
+	h := &orderHandler{name: name, startErr: startErr, mutex: &rec.mutex, events: &rec.events}
+	return NewContextRunner(h, name, time.Hour)
+}
+
+func newTestGroup(name string) *Group {
+	g := NewGroup(name)
+	g.Registry = NewRegistry()
+	return g
+}
+
+func TestGroupOrder(t *testing.T) {
+	req := require.New(t)
+
+	rec := &groupRecorder{}
+	g := newTestGroup("TestGroupOrder")
+	req.NoError(g.Add(rec.runner("api", nil), "db", "cache"))
+	req.NoError(g.Add(rec.runner("cache", nil), "db"))
+	req.NoError(g.Add(rec.runner("db", nil)))
+	req.Error(g.Add(rec.runner("db", nil)))
+
+	req.NoError(g.Start())
+	req.ErrorIs(g.Start(), ErrIsRunning)
+	req.NoError(g.Stop())
+
+	req.Equal([]string{
+		"start db", "start cache", "start api",
+		"exit api", "exit cache", "exit db",
+	}, rec.events)
+}
+
+func TestGroupRollback(t *testing.T) {
+	req := require.New(t)
+
+	rec := &groupRecorder{}
+	g := newTestGroup("TestGroupRollback")
+	req.NoError(g.Add(rec.runner("db", nil)))
+	req.NoError(g.Add(rec.runner("cache", nil), "db"))
+	req.NoError(g.Add(rec.runner("api", errors.New("listen failed")), "cache"))
+
+	err := g.Start()
+	req.Error(err)
+	req.Contains(err.Error(), "listen failed")
+	req.Equal([]string{
+		"start db", "start cache", "start api",
+		"exit cache", "exit db",
+	}, rec.events)
+	req.Equal(0, g.Registry.Len())
+}
+
+func TestGroupInvalidDependency(t *testing.T) {
+	req := require.New(t)
+
+	rec := &groupRecorder{}
+	g := newTestGroup("TestGroupInvalidDependency")
+	req.NoError(g.Add(rec.runner("a", nil), "b"))
+	req.NoError(g.Add(rec.runner("b", nil), "a"))
+	err := g.Start()
+	req.Error(err)
+	req.Contains(err.Error(), "dependency cycle")
+
+	g = newTestGroup("TestGroupInvalidDependency")
+	req.NoError(g.Add(rec.runner("a", nil), "missing"))
+	err = g.Start()
+	req.Error(err)
+	req.Contains(err.Error(), "unknown runner missing")
+	req.Empty(rec.events)
+}
+
+func TestGroupStopDeadline(t *testing.T) {
+	req := require.New(t)
+
+	// 忽略 ctx 的 Handle 会阻塞 Stop
+	release := make(chan struct{})
+	h := &testHandler{
+		handle: func(ctx context.Context) error {
+			<-release
+			return nil
+		},
+	}
+
+	g := newTestGroup("TestGroupStopDeadline")
+	g.StopTimeout = 20 * time.Millisecond
+	db := NewContextRunner(&testHandler{}, "db", time.Hour)
+	api := NewContextRunner(h, "api", time.Hour)
+	req.NoError(g.Add(db))
+	req.NoError(g.Add(api, "db"))
+	req.NoError(g.Start())
+	req.Eventually(func() bool {
+		return h.count() == 1
+	}, time.Second, time.Millisecond)
+
+	// api 停止超时时仍会通知其依赖的 db 停止
+	req.ErrorIs(g.Stop(), context.DeadlineExceeded)
+	req.Equal(StateAbandoned, api.State())
+	req.Eventually(func() bool {
+		return db.State() == StateStopped
+	}, time.Second, time.Millisecond)
+
+	// 未停止的成员被保留, 再次 Stop 时等待其停止
+	req.ErrorIs(g.Stop(), context.DeadlineExceeded)
+	close(release)
+	req.NoError(g.Stop())
+	req.Equal(StateStopped, api.State())
+	req.NoError(g.Start())
+	req.NoError(g.Stop())
+}
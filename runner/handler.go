@@ -1,6 +1,9 @@
 package runner
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
 type Handler interface {
 	// Handle 为 Runner 中循环执行的函数
@@ -9,10 +12,72 @@ type Handler interface {
 	// OnStart 在 Runner 启动前回调
 	OnStart() error
 
-	// OnExit 在 Runner 退出前回调
+	// OnExit 在 Runner 循环退出后回调
 	OnExit()
 }
 
+// ContextHandler 为支持 context 的 Handler.
+// Runner 停止时会取消传入 HandleContext 的 ctx, 使执行中的 Handle 可以及时返回
+type ContextHandler interface {
+	// HandleContext 为 Runner 中循环执行的函数
+	HandleContext(ctx context.Context) error
+
+	// OnStart 在 Runner 启动前回调
+	OnStart() error
+
+	// OnExit 在 Runner 循环退出后回调
+	OnExit()
+}
+
+// ErrorHandler 为 Handle 返回 error 的 Handler.
+// Handle 返回 error 时 Runner 会记录失败, 并按 Runner.Backoff 延迟下一次执行
+type ErrorHandler interface {
+	// Handle 为 Runner 中循环执行的函数
+	Handle() error
+
+	// OnStart 在 Runner 启动前回调
+	OnStart() error
+
+	// OnExit 在 Runner 循环退出后回调
+	OnExit()
+}
+
+// AdaptHandler 将 Handler 适配为 ContextHandler.
+// 如果 h 本身已实现 ContextHandler, 则直接返回
+func AdaptHandler(h Handler) ContextHandler {
+	if ch, ok := h.(ContextHandler); ok {
+		return ch
+	}
+	return &handlerAdapter{Handler: h}
+}
+
+// handlerAdapter 将 Handler 适配为 ContextHandler, ctx 会被忽略
+type handlerAdapter struct {
+	Handler
+}
+
+func (a *handlerAdapter) HandleContext(ctx context.Context) error {
+	a.Handler.Handle()
+	return nil
+}
+
+// AdaptErrorHandler 将 ErrorHandler 适配为 ContextHandler
+func AdaptErrorHandler(h ErrorHandler) ContextHandler {
+	if ch, ok := h.(ContextHandler); ok {
+		return ch
+	}
+	return &errorHandlerAdapter{ErrorHandler: h}
+}
+
+// errorHandlerAdapter 将 ErrorHandler 适配为 ContextHandler, ctx 会被忽略
+type errorHandlerAdapter struct {
+	ErrorHandler
+}
+
+func (a *errorHandlerAdapter) HandleContext(ctx context.Context) error {
+	return a.ErrorHandler.Handle()
+}
+
 type NoopHandler struct {
 }
 
@@ -20,6 +85,14 @@ func (h *NoopHandler) Handle() {
 	time.Sleep(30 * time.Minute)
 }
 
+func (h *NoopHandler) HandleContext(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+	case <-time.After(30 * time.Minute):
+	}
+	return nil
+}
+
 func (h *NoopHandler) OnStart() error {
 	return nil
 }
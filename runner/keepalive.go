@@ -2,24 +2,13 @@ package runner
 
 import "time"
 
-// CheckaliveForever 用于周期性对所有 runner keepalive
+// CheckaliveForever 用于周期性对 DefaultRegistry 中所有 runner keepalive
+//
+// Deprecated: 使用可以停止的 Watchdog
 func CheckaliveForever(interval time.Duration, handleTimeout func(name string, runner *Runner, curTime time.Time)) {
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
-	for range ticker.C {
-		RunningRunners.Range(
-			func(key, value interface{}) bool {
-				name := key.(string)
-				runner := value.(*Runner)
-
-				curTime := time.Now()
-				if runner.IsTimeout(curTime) {
-					handleTimeout(name, runner, curTime)
-				}
-
-				return true
-			},
-		)
-	}
-
+	DefaultRegistry.CheckaliveForever(interval, func(name string, runner IRunner, curTime time.Time) {
+		if r, ok := runner.(*Runner); ok {
+			handleTimeout(name, r, curTime)
+		}
+	})
 }
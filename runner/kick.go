@@ -0,0 +1,109 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+var (
+	// ErrNotRunning 表示 Runner 未在运行
+	ErrNotRunning = errors.New("runner is not running")
+)
+
+// kicker 用于在 Interval 到达前立即触发一次 Handle.
+// 一次 Handle 执行期间的多次 kick 会合并为一次后续执行
+type kicker struct {
+	ch chan struct{}
+
+	mutex   sync.Mutex
+	active  bool         // 循环是否在运行
+	waiters []chan error // 等待下一次 Handle 完成的 KickAndWait
+}
+
+func newKicker() *kicker {
+	return &kicker{
+		ch: make(chan struct{}, 1),
+	}
+}
+
+// kick 非阻塞地触发一次执行, 已有未处理的触发时直接返回
+func (k *kicker) kick() {
+	select {
+	case k.ch <- struct{}{}:
+	default:
+	}
+}
+
+// kickAndWait 触发一次执行并等待其完成, 返回该次 Handle 的 error
+func (k *kicker) kickAndWait(ctx context.Context) error {
+	w := make(chan error, 1)
+
+	k.mutex.Lock()
+	if !k.active {
+		k.mutex.Unlock()
+		return ErrNotRunning
+	}
+	k.waiters = append(k.waiters, w)
+	k.mutex.Unlock()
+
+	k.kick()
+
+	select {
+	case err := <-w:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// open 在 Start 时调用, 丢弃未运行期间的触发
+func (k *kicker) open() {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	select {
+	case <-k.ch:
+	default:
+	}
+	k.active = true
+}
+
+// close 在循环退出时调用, 通知所有等待者 ErrNotRunning
+func (k *kicker) close() {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	k.active = false
+	k.notify(k.waiters, ErrNotRunning)
+	k.waiters = nil
+}
+
+// take 在一次 Handle 开始前调用, 取出此前所有的等待者
+func (k *kicker) take() []chan error {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	waiters := k.waiters
+	k.waiters = nil
+	return waiters
+}
+
+// notify 通知 waiters 本次 Handle 的结果
+func (k *kicker) notify(waiters []chan error, err error) {
+	for _, w := range waiters {
+		w <- err
+	}
+}
+
+// Kick 立即触发一次 Handle, 不等待 Interval. Handle 执行期间的多次 Kick 只会触发一次后续执行.
+// Runner 未运行时 Kick 不产生作用
+func (r *Runner) Kick() {
+	r.kicker.kick()
+}
+
+// KickAndWait 立即触发一次 Handle 并等待其完成, 返回该次 Handle 的 error.
+// Runner 未运行或在完成前停止时返回 ErrNotRunning
+func (r *Runner) KickAndWait(ctx context.Context) error {
+	return r.kicker.kickAndWait(ctx)
+}
@@ -0,0 +1,62 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestKickCoalesce(t *testing.T) {
+	req := require.New(t)
+
+	entered := make(chan struct{}, 10)
+	release := make(chan struct{})
+	h := &testHandler{
+		handle: func(ctx context.Context) error {
+			entered <- struct{}{}
+			<-release
+			return nil
+		},
+	}
+	r := NewContextRunner(h, "TestKickCoalesce", time.Hour)
+	req.NoError(r.StartIn(NewRegistry()))
+	defer r.Stop()
+
+	// 第一次 Handle 执行期间的多次 Kick 合并为一次后续执行
+	<-entered
+	for i := 0; i < 5; i++ {
+		r.Kick()
+	}
+	close(release)
+
+	req.Eventually(func() bool {
+		return h.count() == 2
+	}, time.Second, time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	req.Equal(2, h.count())
+}
+
+func TestKickAndWait(t *testing.T) {
+	req := require.New(t)
+
+	h := &testHandler{
+		handle: func(ctx context.Context) error {
+			return errors.New("handle failed")
+		},
+	}
+	r := NewContextRunner(h, "TestKickAndWait", time.Hour)
+	req.ErrorIs(r.KickAndWait(context.Background()), ErrNotRunning)
+
+	req.NoError(r.StartIn(NewRegistry()))
+	for i := 0; i < 3; i++ {
+		before := h.count()
+		req.EqualError(r.KickAndWait(context.Background()), "handle failed")
+		req.True(h.count() > before)
+	}
+
+	r.Stop()
+	req.ErrorIs(r.KickAndWait(context.Background()), ErrNotRunning)
+}
@@ -0,0 +1,112 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// ErrLeaseNotSupported 表示当前平台不支持该 Lease
+var ErrLeaseNotSupported = errors.New("lease not supported on this platform")
+
+// Lease 为互斥的租约, 同一时刻最多只有一个持有者. 实现需要是并发安全的
+type Lease interface {
+	// Acquire 尝试获取租约, 不阻塞等待. 租约被其他持有者持有时返回 false
+	Acquire(ctx context.Context) (bool, error)
+
+	// Renew 续约, 返回 false 表示已经失去租约
+	Renew(ctx context.Context) (bool, error)
+
+	// Release 释放租约, 未持有时不产生作用
+	Release(ctx context.Context) error
+}
+
+// leaseKeeper 在一次运行中维护 Runner 对 Lease 的持有
+type leaseKeeper struct {
+	runner *Runner
+	lease  Lease
+
+	mutex  sync.Mutex
+	ctx    context.Context // 持有租约期间有效, 失去租约时被取消
+	cancel context.CancelFunc
+}
+
+// acquire 返回持有租约期间有效的 ctx, 未持有时尝试获取
+func (k *leaseKeeper) acquire(parent context.Context) (context.Context, bool) {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	if k.ctx != nil {
+		return k.ctx, true
+	}
+
+	ok, err := k.lease.Acquire(parent)
+	if err != nil {
+		k.runner.log(slog.LevelWarn, "runner lease acquire failed", errorAttr(err))
+		return nil, false
+	}
+	if !ok {
+		return nil, false
+	}
+
+	k.ctx, k.cancel = context.WithCancel(parent)
+	k.runner.log(slog.LevelInfo, "runner lease acquired")
+	return k.ctx, true
+}
+
+// renew 续约, 失去租约时取消持有期间的 ctx, 执行中的 Handle 会随之退出
+func (k *leaseKeeper) renew(parent context.Context) {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	if k.ctx == nil {
+		return
+	}
+
+	ok, err := k.lease.Renew(parent)
+	if ok && err == nil {
+		return
+	}
+
+	// 续约出错时同样让出租约, 避免与其他持有者同时执行
+	k.cancel()
+	k.ctx = nil
+	_ = k.lease.Release(parent)
+	if err != nil {
+		k.runner.log(slog.LevelWarn, "runner lease lost", errorAttr(err))
+	} else {
+		k.runner.log(slog.LevelWarn, "runner lease lost")
+	}
+}
+
+// keep 每隔 interval 续约, 直到 ctx 结束
+func (k *leaseKeeper) keep(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			k.renew(ctx)
+		}
+	}
+}
+
+// release 释放持有的租约
+func (k *leaseKeeper) release() {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	if k.ctx == nil {
+		return
+	}
+	k.cancel()
+	k.ctx = nil
+	if err := k.lease.Release(context.Background()); err != nil {
+		k.runner.log(slog.LevelWarn, "runner lease release failed", errorAttr(err))
+	}
+}
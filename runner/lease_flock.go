@@ -0,0 +1,99 @@
+//go:build unix
+
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"syscall"
+)
+
+// FileLease 是基于 flock 的 Lease, 用于同一主机或共享卷上的多个进程之间选出唯一的持有者.
+//
+// 持有者退出或崩溃时内核会自动释放锁. 锁文件被删除或替换时, Renew 会返回 false
+type FileLease struct {
+	path string
+
+	mutex sync.Mutex
+	file  *os.File // 持有时不为 nil
+}
+
+// NewFileLease 创建使用 path 作为锁文件的 FileLease, 文件不存在时会在 Acquire 时创建
+func NewFileLease(path string) *FileLease {
+	return &FileLease{
+		path: path,
+	}
+}
+
+func (l *FileLease) Acquire(ctx context.Context) (bool, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.file != nil {
+		return true, nil
+	}
+
+	file, err := os.OpenFile(l.path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return false, fmt.Errorf("open lease file %s: %w", l.path, err)
+	}
+
+	err = syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if errors.Is(err, syscall.EWOULDBLOCK) {
+		_ = file.Close()
+		return false, nil
+	}
+	if err != nil {
+		_ = file.Close()
+		return false, fmt.Errorf("flock lease file %s: %w", l.path, err)
+	}
+
+	// 记录持有者的 pid, 便于排查
+	if err := file.Truncate(0); err == nil {
+		_, _ = file.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+	}
+
+	l.file = file
+	return true, nil
+}
+
+func (l *FileLease) Renew(ctx context.Context) (bool, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.file == nil {
+		return false, nil
+	}
+
+	// 锁文件被删除或替换后, 其他进程可以在新文件上获取锁
+	held, err := l.file.Stat()
+	if err != nil {
+		return false, fmt.Errorf("stat lease file %s: %w", l.path, err)
+	}
+	current, err := os.Stat(l.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("stat lease file %s: %w", l.path, err)
+	}
+	return os.SameFile(held, current), nil
+}
+
+func (l *FileLease) Release(ctx context.Context) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+
+	file := l.file
+	l.file = nil
+	_ = syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+	return file.Close()
+}
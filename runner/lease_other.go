@@ -0,0 +1,31 @@
+//go:build !unix
+
+package runner
+
+import (
+	"context"
+)
+
+// FileLease 是基于 flock 的 Lease, 当前平台不支持, 所有方法返回 ErrLeaseNotSupported
+type FileLease struct {
+	path string
+}
+
+// NewFileLease 创建使用 path 作为锁文件的 FileLease
+func NewFileLease(path string) *FileLease {
+	return &FileLease{
+		path: path,
+	}
+}
+
+func (l *FileLease) Acquire(ctx context.Context) (bool, error) {
+	return false, ErrLeaseNotSupported
+}
+
+func (l *FileLease) Renew(ctx context.Context) (bool, error) {
+	return false, ErrLeaseNotSupported
+}
+
+func (l *FileLease) Release(ctx context.Context) error {
+	return nil
+}
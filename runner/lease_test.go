@@ -0,0 +1,156 @@
+//go:build unix
+
+package runner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/KanShiori/kit/timer"
+)
+
+func TestFileLease(t *testing.T) {
+	req := require.New(t)
+	ctx := context.Background()
+
+	path := filepath.Join(t.TempDir(), "lease")
+	a, b := NewFileLease(path), NewFileLease(path)
+
+	ok, err := a.Acquire(ctx)
+	req.NoError(err)
+	req.True(ok)
+	ok, err = b.Acquire(ctx)
+	req.NoError(err)
+	req.False(ok)
+
+	ok, err = a.Renew(ctx)
+	req.NoError(err)
+	req.True(ok)
+
+	req.NoError(a.Release(ctx))
+	ok, err = b.Acquire(ctx)
+	req.NoError(err)
+	req.True(ok)
+
+	// 锁文件被删除后失去租约
+	req.NoError(os.Remove(path))
+	ok, err = b.Renew(ctx)
+	req.NoError(err)
+	req.False(ok)
+	req.NoError(b.Release(ctx))
+}
+
+func TestRunnerLease(t *testing.T) {
+	req := require.New(t)
+
+	path := filepath.Join(t.TempDir(), "lease")
+	var active int32
+	newLeader := func(name string) (*Runner, *testHandler) {
+		h := &testHandler{
+			handle: func(ctx context.Context) error {
+				// 同一时刻只有一个 Runner 执行 Handle
+				if atomic.AddInt32(&active, 1) != 1 {
+					panic("lease held by two runners")
+				}
+				defer atomic.AddInt32(&active, -1)
+
+				select {
+				case <-ctx.Done():
+				case <-time.After(time.Millisecond):
+				}
+				return nil
+			},
+		}
+		r := NewContextRunner(h, name, time.Millisecond, WithLease(NewFileLease(path), 5*time.Millisecond))
+		return r, h
+	}
+
+	reg := NewRegistry()
+	a, ha := newLeader("a")
+	b, hb := newLeader("b")
+	req.NoError(a.StartIn(reg))
+	req.Eventually(func() bool {
+		return ha.count() > 0
+	}, time.Second, time.Millisecond)
+	req.NoError(b.StartIn(reg))
+	defer b.Stop()
+
+	time.Sleep(20 * time.Millisecond)
+	req.Zero(hb.count())
+	req.False(b.IsTimeout(time.Now()))
+
+	// a 停止后释放 lease, 由 b 接替
+	a.Stop()
+	req.Eventually(func() bool {
+		return hb.count() > 0
+	}, time.Second, time.Millisecond)
+	req.NoError(b.Err())
+}
+
+func TestRunnerLeaseLost(t *testing.T) {
+	req := require.New(t)
+
+	path := filepath.Join(t.TempDir(), "lease")
+	entered := make(chan struct{}, 10)
+	canceled := make(chan struct{}, 10)
+	h := &testHandler{
+		handle: func(ctx context.Context) error {
+			entered <- struct{}{}
+			<-ctx.Done()
+			canceled <- struct{}{}
+			return ctx.Err()
+		},
+	}
+	r := NewContextRunner(h, "TestRunnerLeaseLost", time.Millisecond, WithLease(NewFileLease(path), 5*time.Millisecond))
+	req.NoError(r.StartIn(NewRegistry()))
+	defer r.Stop()
+	<-entered
+
+	// 删除锁文件后让出 lease, 取消执行中的 Handle, 之后重新获取
+	req.NoError(os.Remove(path))
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		req.Fail("Handle not canceled after lease lost")
+	}
+	req.Zero(r.ConsecutiveFailures())
+
+	select {
+	case <-entered:
+	case <-time.After(time.Second):
+		req.Fail("lease not reacquired")
+	}
+	req.Equal(StateRunning, r.State())
+}
+
+func TestRunnerLeaseStandbyTimer(t *testing.T) {
+	req := require.New(t)
+
+	path := filepath.Join(t.TempDir(), "lease")
+	leader := NewFileLease(path)
+	ok, err := leader.Acquire(context.Background())
+	req.NoError(err)
+	req.True(ok)
+
+	// 未持有 lease 时不占用 Timer 的时间段, 接替后立即执行
+	h := &testHandler{}
+	r := NewContextRunner(h, "TestRunnerLeaseStandbyTimer", time.Millisecond,
+		WithLease(NewFileLease(path), 5*time.Millisecond),
+		WithTimer(timer.NewTimeSpan(time.Hour, time.Time{})))
+	req.NoError(r.StartIn(NewRegistry()))
+	defer r.Stop()
+
+	time.Sleep(20 * time.Millisecond)
+	req.Zero(h.count())
+
+	req.NoError(leader.Release(context.Background()))
+	req.Eventually(func() bool {
+		return h.count() == 1
+	}, time.Second, time.Millisecond)
+}
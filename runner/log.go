@@ -0,0 +1,45 @@
+package runner
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"time"
+)
+
+// 结构化日志中统一使用的属性名
+const (
+	LogKeyRunner   = "runner"
+	LogKeyAttempt  = "attempt"
+	LogKeyDuration = "duration"
+	LogKeyError    = "error"
+)
+
+// newTextLogger 返回以 slog.TextHandler 写入 w 的 *slog.Logger, w 为 nil 时返回 nil
+func newTextLogger(w io.Writer) *slog.Logger {
+	if w == nil {
+		return nil
+	}
+	return slog.New(slog.NewTextHandler(w, nil))
+}
+
+// logEvent 输出一条名为 name 的 Runner 的日志, logger 为 nil 时不输出
+func logEvent(logger *slog.Logger, level slog.Level, msg, name string, attrs ...slog.Attr) {
+	if logger == nil {
+		return
+	}
+	attrs = append([]slog.Attr{slog.String(LogKeyRunner, name)}, attrs...)
+	logger.LogAttrs(context.Background(), level, msg, attrs...)
+}
+
+func attemptAttr(attempt int) slog.Attr {
+	return slog.Int(LogKeyAttempt, attempt)
+}
+
+func durationAttr(d time.Duration) slog.Attr {
+	return slog.Duration(LogKeyDuration, d)
+}
+
+func errorAttr(err error) slog.Attr {
+	return slog.Any(LogKeyError, err)
+}
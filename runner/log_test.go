@@ -0,0 +1,105 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// syncBuffer 为并发安全的 bytes.Buffer
+type syncBuffer struct {
+	mutex sync.Mutex
+	buf   bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buf.String()
+}
+
+func TestSlog(t *testing.T) {
+	req := require.New(t)
+
+	var buf syncBuffer
+	r := Func("TestSlog", time.Millisecond, func(ctx context.Context) error {
+		return errors.New("handle failed")
+	},
+		WithSlog(slog.New(slog.NewJSONHandler(&buf, nil))),
+		WithBackoff(NewBackoff(time.Millisecond, time.Millisecond)),
+		WithMaxRuns(2),
+	)
+	req.NoError(r.StartIn(NewRegistry()))
+	req.NoError(r.Wait())
+
+	var records []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var record map[string]interface{}
+		req.NoError(json.Unmarshal([]byte(line), &record))
+		req.Equal("TestSlog", record[LogKeyRunner])
+		records = append(records, record)
+	}
+
+	var msgs []string
+	for _, record := range records {
+		msgs = append(msgs, record["msg"].(string))
+	}
+	req.Equal([]string{
+		"runner started",
+		"runner handle failed",
+		"runner backoff",
+		"runner handle failed",
+		"runner stopped",
+	}, msgs)
+
+	req.EqualValues(1, records[0][LogKeyAttempt])
+	req.EqualValues(2, records[3][LogKeyAttempt])
+	req.Equal("handle failed", records[3][LogKeyError])
+	req.Contains(records[3], LogKeyDuration)
+}
+
+func TestLoggerWriter(t *testing.T) {
+	req := require.New(t)
+
+	var buf syncBuffer
+	r := Func("TestLoggerWriter", time.Millisecond, func(ctx context.Context) error {
+		panic("boom")
+	}, WithLogger(&buf))
+	req.NoError(r.StartIn(NewRegistry()))
+	req.Error(r.Wait())
+
+	// io.Writer 以 slog.TextHandler 的格式输出
+	out := buf.String()
+	req.Contains(out, `msg="runner panic" runner=TestLoggerWriter error="panic: boom"`)
+	req.Contains(out, `msg="runner failed" runner=TestLoggerWriter`)
+}
+
+func TestSlogTimeout(t *testing.T) {
+	req := require.New(t)
+
+	var buf syncBuffer
+	reg := NewRegistry()
+	r := Func("TestSlogTimeout", time.Hour, func(ctx context.Context) error {
+		return nil
+	}, WithLogger(&buf))
+	req.NoError(r.StartIn(reg))
+	defer r.Stop()
+
+	// Watchdog 检测到的超时同样输出到 Runner 的日志
+	NewWatchdog(reg, time.Hour).Check(time.Now().Add(2 * time.Hour))
+	req.Contains(buf.String(), `level=WARN msg="runner timeout" runner=TestSlogTimeout`)
+}
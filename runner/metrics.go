@@ -0,0 +1,235 @@
+package runner
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultDurationBuckets 为 Handle 耗时直方图默认的 bucket 上界, 单位为秒
+var DefaultDurationBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60}
+
+// Metrics 记录单个 Runner 的运行指标, 所有方法都是并发安全且不会阻塞的
+type Metrics struct {
+	handles  atomic.Uint64
+	errors   atomic.Uint64
+	panics   atomic.Uint64
+	timeouts atomic.Uint64
+	overruns atomic.Uint64
+
+	interval  atomic.Int64
+	nextDelay atomic.Int64
+
+	duration histogram
+}
+
+// NewMetrics 创建使用 buckets 作为耗时直方图 bucket 的 Metrics, buckets 为空时使用 DefaultDurationBuckets
+func NewMetrics(buckets ...float64) *Metrics {
+	if len(buckets) == 0 {
+		buckets = DefaultDurationBuckets
+	}
+	m := &Metrics{}
+	m.duration.init(buckets)
+	return m
+}
+
+// ObserveHandle 记录一次 Handle 的耗时与结果
+func (m *Metrics) ObserveHandle(d time.Duration, err error) {
+	m.handles.Add(1)
+	if err != nil {
+		m.errors.Add(1)
+	}
+	m.duration.observe(d.Seconds())
+}
+
+// IncPanic 记录一次 panic
+func (m *Metrics) IncPanic() {
+	m.panics.Add(1)
+}
+
+// IncTimeout 记录一次 Watchdog 检测到的超时
+func (m *Metrics) IncTimeout() {
+	m.timeouts.Add(1)
+}
+
+// IncOverrun 记录一次执行超过 HandleTimeout 的 Handle
+func (m *Metrics) IncOverrun() {
+	m.overruns.Add(1)
+}
+
+// SetInterval 记录 Runner 设置的两次 Handle 之间的间隔
+func (m *Metrics) SetInterval(d time.Duration) {
+	m.interval.Store(int64(d))
+}
+
+// SetNextDelay 记录当前距离下一次 Handle 的等待时间, 包括 Backoff 与 FixedRate 计算的等待
+func (m *Metrics) SetNextDelay(d time.Duration) {
+	m.nextDelay.Store(int64(d))
+}
+
+// Snapshot 返回当前指标的拷贝
+func (m *Metrics) Snapshot() MetricsSnapshot {
+	s := MetricsSnapshot{
+		Handles:  m.handles.Load(),
+		Errors:   m.errors.Load(),
+		Panics:   m.panics.Load(),
+		Timeouts: m.timeouts.Load(),
+		Overruns: m.overruns.Load(),
+		Interval: time.Duration(m.interval.Load()),
+
+		NextDelay: time.Duration(m.nextDelay.Load()),
+	}
+	s.DurationBuckets, s.DurationCounts, s.DurationSum, s.DurationCount = m.duration.snapshot()
+	return s
+}
+
+// MetricsSnapshot 为某一时刻的 Metrics
+type MetricsSnapshot struct {
+	Handles  uint64
+	Errors   uint64
+	Panics   uint64
+	Timeouts uint64
+	Overruns uint64
+	Interval time.Duration
+
+	// NextDelay 为最近一次计算的距离下一次 Handle 的等待时间
+	NextDelay time.Duration
+
+	// DurationBuckets 为 bucket 上界(秒), DurationCounts 为对应的累计次数
+	DurationBuckets []float64
+	DurationCounts  []uint64
+	DurationSum     float64
+	DurationCount   uint64
+}
+
+// histogram 为累计 bucket 的直方图
+type histogram struct {
+	mutex   sync.Mutex
+	buckets []float64
+	counts  []uint64 // 非累计, 最后一个为 +Inf
+	sum     float64
+	count   uint64
+}
+
+func (h *histogram) init(buckets []float64) {
+	h.buckets = append([]float64(nil), buckets...)
+	sort.Float64s(h.buckets)
+	h.counts = make([]uint64, len(h.buckets)+1)
+}
+
+func (h *histogram) observe(v float64) {
+	i := sort.SearchFloat64s(h.buckets, v)
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.counts[i]++
+	h.sum += v
+	h.count++
+}
+
+// snapshot 返回 bucket 上界与累计次数
+func (h *histogram) snapshot() ([]float64, []uint64, float64, uint64) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	cumulative := make([]uint64, len(h.buckets))
+	var total uint64
+	for i := range h.buckets {
+		total += h.counts[i]
+		cumulative[i] = total
+	}
+	return append([]float64(nil), h.buckets...), cumulative, h.sum, h.count
+}
+
+// MetricsHandler 返回以 Prometheus text 格式暴露 reg 中所有 Runner 指标的 http.Handler
+func MetricsHandler(reg *Registry) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_ = WriteMetrics(w, reg)
+	})
+}
+
+// WriteMetrics 将 reg 中所有实现了 Metrics() *Metrics 的 Runner 的指标以 Prometheus text 格式写入 w
+func WriteMetrics(w io.Writer, reg *Registry) error {
+	var names []string
+	snapshots := make(map[string]MetricsSnapshot)
+	reg.Range(func(name string, runner IRunner) bool {
+		if r, ok := runner.(interface{ Metrics() *Metrics }); ok {
+			names = append(names, name)
+			snapshots[name] = r.Metrics().Snapshot()
+		}
+		return true
+	})
+
+	bw := bufio.NewWriter(w)
+
+	counters := []struct {
+		name  string
+		help  string
+		value func(s MetricsSnapshot) uint64
+	}{
+		{"runner_handle_total", "Total number of Handle calls.", func(s MetricsSnapshot) uint64 { return s.Handles }},
+		{"runner_handle_errors_total", "Total number of Handle calls that returned an error.", func(s MetricsSnapshot) uint64 { return s.Errors }},
+		{"runner_panics_total", "Total number of panics recovered from Handle.", func(s MetricsSnapshot) uint64 { return s.Panics }},
+		{"runner_timeouts_total", "Total number of timeouts detected by the watchdog.", func(s MetricsSnapshot) uint64 { return s.Timeouts }},
+		{"runner_overruns_total", "Total number of Handle calls that exceeded the handle timeout.", func(s MetricsSnapshot) uint64 { return s.Overruns }},
+	}
+	for _, c := range counters {
+		writeHeader(bw, c.name, c.help, "counter")
+		for _, name := range names {
+			fmt.Fprintf(bw, "%s{runner=\"%s\"} %d\n", c.name, escapeLabel(name), c.value(snapshots[name]))
+		}
+	}
+
+	gauges := []struct {
+		name  string
+		help  string
+		value func(s MetricsSnapshot) time.Duration
+	}{
+		{"runner_interval_seconds", "Configured interval between Handle calls.", func(s MetricsSnapshot) time.Duration { return s.Interval }},
+		{"runner_next_delay_seconds", "Last computed delay before the next Handle call.", func(s MetricsSnapshot) time.Duration { return s.NextDelay }},
+	}
+	for _, g := range gauges {
+		writeHeader(bw, g.name, g.help, "gauge")
+		for _, name := range names {
+			fmt.Fprintf(bw, "%s{runner=\"%s\"} %s\n", g.name, escapeLabel(name), formatFloat(g.value(snapshots[name]).Seconds()))
+		}
+	}
+
+	writeHeader(bw, "runner_handle_duration_seconds", "Duration of Handle calls.", "histogram")
+	for _, name := range names {
+		s := snapshots[name]
+		label := escapeLabel(name)
+		for i, le := range s.DurationBuckets {
+			fmt.Fprintf(bw, "runner_handle_duration_seconds_bucket{runner=\"%s\",le=\"%s\"} %d\n", label, formatFloat(le), s.DurationCounts[i])
+		}
+		fmt.Fprintf(bw, "runner_handle_duration_seconds_bucket{runner=\"%s\",le=\"+Inf\"} %d\n", label, s.DurationCount)
+		fmt.Fprintf(bw, "runner_handle_duration_seconds_sum{runner=\"%s\"} %s\n", label, formatFloat(s.DurationSum))
+		fmt.Fprintf(bw, "runner_handle_duration_seconds_count{runner=\"%s\"} %d\n", label, s.DurationCount)
+	}
+
+	return bw.Flush()
+}
+
+func writeHeader(w io.Writer, name, help, typ string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, typ)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(v string) string {
+	return labelEscaper.Replace(v)
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,77 @@
+package runner
+
+import (
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsSnapshot(t *testing.T) {
+	req := require.New(t)
+
+	m := NewMetrics(0.1, 1)
+	m.ObserveHandle(50*time.Millisecond, nil)
+	m.ObserveHandle(500*time.Millisecond, errors.New("failed"))
+	m.ObserveHandle(2*time.Second, nil)
+	m.IncPanic()
+	m.IncTimeout()
+	m.SetInterval(time.Second)
+	m.SetNextDelay(time.Millisecond)
+
+	s := m.Snapshot()
+	req.Equal(uint64(3), s.Handles)
+	req.Equal(uint64(1), s.Errors)
+	req.Equal(uint64(1), s.Panics)
+	req.Equal(uint64(1), s.Timeouts)
+	req.Equal(time.Second, s.Interval)
+	req.Equal(time.Millisecond, s.NextDelay)
+	req.Equal([]float64{0.1, 1}, s.DurationBuckets)
+	req.Equal([]uint64{1, 2}, s.DurationCounts)
+	req.Equal(uint64(3), s.DurationCount)
+	req.InDelta(2.55, s.DurationSum, 1e-9)
+}
+
+func TestMetricsHandler(t *testing.T) {
+	req := require.New(t)
+
+	reg := NewRegistry()
+	h := &testHandler{}
+	r := NewContextRunner(h, `quote"d`, time.Hour)
+	req.NoError(r.StartIn(reg))
+	defer r.Stop()
+	req.Eventually(func() bool {
+		s := r.Metrics().Snapshot()
+		return s.Handles == 1 && s.NextDelay == time.Hour
+	}, time.Second, time.Millisecond)
+
+	rec := httptest.NewRecorder()
+	MetricsHandler(reg).ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, err := io.ReadAll(rec.Result().Body)
+	req.NoError(err)
+
+	req.True(strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain"))
+	text := string(body)
+	req.Contains(text, "# TYPE runner_handle_total counter\n")
+	req.Contains(text, `runner_handle_total{runner="quote\"d"} 1`+"\n")
+	req.Contains(text, `runner_panics_total{runner="quote\"d"} 0`+"\n")
+	req.Contains(text, `runner_handle_duration_seconds_bucket{runner="quote\"d",le="+Inf"} 1`+"\n")
+	req.Contains(text, `runner_handle_duration_seconds_count{runner="quote\"d"} 1`+"\n")
+	req.Contains(text, `runner_interval_seconds{runner="quote\"d"} 3600`+"\n")
+	req.Contains(text, `runner_next_delay_seconds{runner="quote\"d"} 3600`+"\n")
+}
+
+func TestMetricsInterval(t *testing.T) {
+	req := require.New(t)
+
+	// 未运行时同样记录设置的 Interval
+	r := NewContextRunner(&testHandler{}, "TestMetricsInterval", time.Minute)
+	req.Equal(time.Minute, r.Metrics().Snapshot().Interval)
+	r.SetInterval(time.Second)
+	req.Equal(time.Second, r.Metrics().Snapshot().Interval)
+	req.Zero(r.Metrics().Snapshot().NextDelay)
+}
@@ -0,0 +1,145 @@
+package runner
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// HandleFunc 为一次 Handle 的执行
+type HandleFunc func(ctx context.Context) error
+
+// Middleware 包装 HandleFunc, 在每次 Handle 前后加入通用的逻辑
+type Middleware func(next HandleFunc) HandleFunc
+
+// Chain 将 mws 组合为一个 Middleware, mws[0] 在最外层
+func Chain(mws ...Middleware) Middleware {
+	return func(next HandleFunc) HandleFunc {
+		for i := len(mws) - 1; i >= 0; i-- {
+			next = mws[i](next)
+		}
+		return next
+	}
+}
+
+type nameKey struct{}
+
+// NameFromContext 返回执行 Handle 的 Runner 的名称, 用于 Middleware 区分不同的 Runner
+func NameFromContext(ctx context.Context) string {
+	name, _ := ctx.Value(nameKey{}).(string)
+	return name
+}
+
+// buildHandle 使用 Registry 与 Runner 的 Middleware 包装 handler, Registry 的 Middleware 在外层
+func buildHandle(name string, handler ContextHandler, reg *Registry, mws []Middleware) HandleFunc {
+	all := append(reg.middlewares(), mws...)
+	next := Chain(all...)(handler.HandleContext)
+
+	return func(ctx context.Context) error {
+		return next(context.WithValue(ctx, nameKey{}, name))
+	}
+}
+
+// Recover 将 Handle 中的 panic 恢复为 *PanicError 返回, Runner 会继续运行而不是进入 StateFailed.
+// 恢复的 panic 仍会记录到 Metrics.Panics, 并输出日志与发布 TopicPanicked
+func Recover() Middleware {
+	return func(next HandleFunc) HandleFunc {
+		return func(ctx context.Context) (err error) {
+			defer func() {
+				if x := recover(); x != nil {
+					stackBuf := make([]byte, 1024*10)
+					size := runtime.Stack(stackBuf, false)
+					err = &PanicError{Value: x, Stack: stackBuf[0:size]}
+				}
+			}()
+			return next(ctx)
+		}
+	}
+}
+
+// Timing 在每次 Handle 结束后以耗时与结果回调 observe
+func Timing(observe func(name string, d time.Duration, err error)) Middleware {
+	return func(next HandleFunc) HandleFunc {
+		return func(ctx context.Context) error {
+			start := time.Now()
+			err := next(ctx)
+			observe(NameFromContext(ctx), time.Since(start), err)
+			return err
+		}
+	}
+}
+
+// Logging 将每次 Handle 的耗时与结果输出到 logger, 失败时为 Warn 级别, 属性名见 LogKeyRunner 等常量
+func Logging(logger *slog.Logger) Middleware {
+	return Timing(func(name string, d time.Duration, err error) {
+		if err != nil {
+			logEvent(logger, slog.LevelWarn, "runner handle", name, durationAttr(d), errorAttr(err))
+			return
+		}
+		logEvent(logger, slog.LevelInfo, "runner handle", name, durationAttr(d))
+	})
+}
+
+// Timeout 为每次 Handle 的 ctx 设置 d 的超时
+func Timeout(d time.Duration) Middleware {
+	return func(next HandleFunc) HandleFunc {
+		return func(ctx context.Context) error {
+			ctx, cancel := context.WithTimeout(ctx, d)
+			defer cancel()
+			return next(ctx)
+		}
+	}
+}
+
+// RateLimit 使两次 Handle 的开始至少间隔 every, 不足时等待, 等待期间 ctx 结束则返回 ctx.Err().
+// 等待被取消时, 若之后没有其他等待者, 预留的时间点会被归还. 返回的 Middleware 在所有使用它的 Runner 之间共享限制
+func RateLimit(every time.Duration) Middleware {
+	var mutex sync.Mutex
+	var next time.Time // 下一次允许开始的时间
+
+	// reserve 预留一个时间点, 返回该时间点与需要等待的时间
+	reserve := func() (time.Time, time.Duration) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		now := time.Now()
+		if next.Before(now) {
+			next = now
+		}
+		slot := next
+		next = next.Add(every)
+		return slot, slot.Sub(now)
+	}
+
+	// release 归还未使用的时间点 slot, 其后已有其他预留时无法归还
+	release := func(slot time.Time) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		if next.Equal(slot.Add(every)) {
+			next = slot
+		}
+	}
+
+	return func(h HandleFunc) HandleFunc {
+		return func(ctx context.Context) error {
+			slot, wait := reserve()
+			if wait <= 0 {
+				return h(ctx)
+			}
+
+			t := time.NewTimer(wait)
+			defer t.Stop()
+
+			select {
+			case <-ctx.Done():
+				release(slot)
+				return ctx.Err()
+			case <-t.C:
+			}
+			return h(ctx)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestChain(t *testing.T) {
+	req := require.New(t)
+
+	var order []string
+	mw := func(name string) Middleware {
+		return func(next HandleFunc) HandleFunc {
+			return func(ctx context.Context) error {
+				order = append(order, name)
+				return next(ctx)
+			}
+		}
+	}
+
+	h := Chain(mw("a"), mw("b"), mw("c"))(func(ctx context.Context) error {
+		order = append(order, "handle")
+		return nil
+	})
+	req.NoError(h(context.Background()))
+	req.Equal([]string{"a", "b", "c", "handle"}, order)
+}
+
+func TestMiddlewareOrder(t *testing.T) {
+	req := require.New(t)
+
+	var mutex sync.Mutex
+	var order []string
+	record := func(tag string) Middleware {
+		return func(next HandleFunc) HandleFunc {
+			return func(ctx context.Context) error {
+				mutex.Lock()
+				order = append(order, tag+":"+NameFromContext(ctx))
+				mutex.Unlock()
+				return next(ctx)
+			}
+		}
+	}
+
+	reg := NewRegistry()
+	reg.Use(record("registry"))
+
+	r := NewContextRunner(&testHandler{}, "TestMiddlewareOrder", time.Hour)
+	r.Use(record("runner"))
+	r.MaxRuns = 1
+	req.NoError(r.StartIn(reg.Namespace("ns")))
+	req.NoError(r.Wait())
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	req.Equal([]string{"registry:TestMiddlewareOrder", "runner:TestMiddlewareOrder"}, order)
+}
+
+func TestRecover(t *testing.T) {
+	req := require.New(t)
+
+	h := &testHandler{
+		handle: func(ctx context.Context) error {
+			panic("boom")
+		},
+	}
+	r := NewContextRunner(h, "TestRecover", time.Millisecond)
+	r.Use(Recover())
+	req.NoError(r.StartIn(NewRegistry()))
+	defer r.Stop()
+
+	// panic 作为 error 返回, Runner 继续运行
+	req.Eventually(func() bool {
+		return h.count() > 2
+	}, time.Second, time.Millisecond)
+	req.Equal(StateRunning, r.State())
+
+	var perr *PanicError
+	req.ErrorAs(r.LastError(), &perr)
+	req.Equal("boom", perr.Value)
+	req.GreaterOrEqual(r.Metrics().Snapshot().Panics, uint64(2))
+}
+
+func TestLoggingAndTimeout(t *testing.T) {
+	req := require.New(t)
+
+	var buf bytes.Buffer
+	h := Chain(Logging(slog.New(slog.NewTextHandler(&buf, nil))), Timeout(5*time.Millisecond))(func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	ctx := context.WithValue(context.Background(), nameKey{}, "worker")
+	req.ErrorIs(h(ctx), context.DeadlineExceeded)
+	req.Contains(buf.String(), "level=WARN")
+	req.Contains(buf.String(), LogKeyRunner+"=worker")
+	req.Contains(buf.String(), context.DeadlineExceeded.Error())
+}
+
+func TestRateLimit(t *testing.T) {
+	req := require.New(t)
+
+	var calls int
+	h := RateLimit(10 * time.Millisecond)(func(ctx context.Context) error {
+		calls++
+		return nil
+	})
+
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		req.NoError(h(context.Background()))
+	}
+	req.Equal(4, calls)
+	req.True(time.Since(start) >= 30*time.Millisecond)
+
+	// 等待期间 ctx 结束, 下一个时间点在很久之后
+	calls = 0
+	h = RateLimit(time.Hour)(func(ctx context.Context) error {
+		calls++
+		return nil
+	})
+	req.NoError(h(context.Background()))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req.ErrorIs(h(ctx), context.Canceled)
+	req.Equal(1, calls)
+}
+
+func TestRateLimitRelease(t *testing.T) {
+	req := require.New(t)
+
+	h := RateLimit(50 * time.Millisecond)(func(ctx context.Context) error {
+		return nil
+	})
+
+	start := time.Now()
+	req.NoError(h(context.Background()))
+
+	// 被取消的等待归还时间点, 不推迟之后的 Handle
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	req.ErrorIs(h(ctx), context.DeadlineExceeded)
+	req.NoError(h(context.Background()))
+	elapsed := time.Since(start)
+	req.True(elapsed >= 50*time.Millisecond, "elapsed %s", elapsed)
+	req.True(elapsed < 90*time.Millisecond, "elapsed %s", elapsed)
+}
@@ -0,0 +1,166 @@
+package runner
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/KanShiori/kit/eventbus"
+	"github.com/KanShiori/kit/timer"
+)
+
+// Option 为创建 Runner 时的可选配置
+type Option func(r *Runner)
+
+// Func 创建一个循环执行 fn 的 Runner, 无需实现 Handler.
+//
+//	r := runner.Func("cleanup", time.Minute, cleanup, runner.WithTimeout(10*time.Minute))
+func Func(name string, interval time.Duration, fn func(ctx context.Context) error, opts ...Option) *Runner {
+	return NewContextRunner(funcHandler(fn), name, interval, opts...)
+}
+
+// funcHandler 将函数适配为 ContextHandler
+type funcHandler func(ctx context.Context) error
+
+func (f funcHandler) HandleContext(ctx context.Context) error {
+	return f(ctx)
+}
+
+func (f funcHandler) OnStart() error {
+	return nil
+}
+
+func (f funcHandler) OnExit() {
+}
+
+// hookHandler 在 ContextHandler 的 OnStart 与 OnExit 之后回调额外的函数
+type hookHandler struct {
+	ContextHandler
+	onStart func() error
+	onExit  func()
+}
+
+func (h *hookHandler) OnStart() error {
+	if err := h.ContextHandler.OnStart(); err != nil {
+		return err
+	}
+	if h.onStart != nil {
+		return h.onStart()
+	}
+	return nil
+}
+
+func (h *hookHandler) OnExit() {
+	h.ContextHandler.OnExit()
+	if h.onExit != nil {
+		h.onExit()
+	}
+}
+
+// WithOnStart 在 Handler 的 OnStart 成功后回调 fn, fn 返回 error 时 Start 失败
+func WithOnStart(fn func() error) Option {
+	return func(r *Runner) {
+		r.handler = &hookHandler{ContextHandler: r.handler, onStart: fn}
+	}
+}
+
+// WithOnExit 在 Handler 的 OnExit 后回调 fn
+func WithOnExit(fn func()) Option {
+	return func(r *Runner) {
+		r.handler = &hookHandler{ContextHandler: r.handler, onExit: fn}
+	}
+}
+
+// WithTimeout 设置 IsTimeout 判断阻塞的超时时间
+func WithTimeout(timeout time.Duration) Option {
+	return func(r *Runner) {
+		r.SetTimeout(timeout)
+	}
+}
+
+// WithLogger 设置输出日志的 io.Writer
+func WithLogger(logger io.Writer) Option {
+	return func(r *Runner) {
+		r.SetLogger(logger)
+	}
+}
+
+// WithSlog 设置输出结构化日志的 *slog.Logger
+func WithSlog(logger *slog.Logger) Option {
+	return func(r *Runner) {
+		r.SetSlog(logger)
+	}
+}
+
+// WithLease 设置只在持有 lease 期间执行 Handle, interval 为续约与重新获取的间隔
+func WithLease(lease Lease, interval time.Duration) Option {
+	return func(r *Runner) {
+		r.Lease = lease
+		r.LeaseInterval = interval
+	}
+}
+
+// WithCheckpoints 设置持久化执行记录的 CheckpointStore
+func WithCheckpoints(store CheckpointStore) Option {
+	return func(r *Runner) {
+		r.Checkpoints = store
+	}
+}
+
+// WithEventBus 设置发布生命周期事件的 eventbus.Bus
+func WithEventBus(bus eventbus.Bus) Option {
+	return func(r *Runner) {
+		r.Bus = bus
+	}
+}
+
+// WithBackoff 设置 Handle 返回 error 后的退避
+func WithBackoff(backoff *Backoff) Option {
+	return func(r *Runner) {
+		r.Backoff = backoff
+	}
+}
+
+// WithTimer 设置限制 Handle 执行时间的 Timer
+func WithTimer(t timer.Timer) Option {
+	return func(r *Runner) {
+		r.Timer = t
+	}
+}
+
+// WithFixedRate 使用固定频率调度
+func WithFixedRate(rate FixedRate) Option {
+	return func(r *Runner) {
+		r.FixedRate = &rate
+	}
+}
+
+// WithMaxRuns 设置执行 n 次 Handle 后正常结束
+func WithMaxRuns(n int) Option {
+	return func(r *Runner) {
+		r.MaxRuns = n
+	}
+}
+
+// WithRunUntil 设置到达 deadline 后正常结束
+func WithRunUntil(deadline time.Time) Option {
+	return func(r *Runner) {
+		r.RunUntil = deadline
+	}
+}
+
+// WithHandleTimeout 设置每次 Handle 的超时时间与 overrun 时的回调, onOverrun 可以为 nil
+func WithHandleTimeout(timeout time.Duration, onOverrun func(overrun Overrun)) Option {
+	return func(r *Runner) {
+		r.HandleTimeout = timeout
+		r.OnOverrun = onOverrun
+	}
+}
+
+// WithMiddleware 添加包装 Handle 的 Middleware
+func WithMiddleware(mws ...Middleware) Option {
+	return func(r *Runner) {
+		r.middlewares = append(r.middlewares, mws...)
+	}
+}
@@ -0,0 +1,50 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFunc(t *testing.T) {
+	req := require.New(t)
+
+	var hooks []string
+	r := Func("TestFunc", time.Millisecond, func(ctx context.Context) error {
+		return nil
+	},
+		WithOnStart(func() error {
+			hooks = append(hooks, "start")
+			return nil
+		}),
+		WithOnExit(func() {
+			hooks = append(hooks, "exit")
+		}),
+		WithTimeout(time.Minute),
+		WithMaxRuns(3),
+	)
+	req.Equal(time.Minute, r.Timeout())
+
+	req.NoError(r.StartIn(NewRegistry()))
+	req.NoError(r.Wait())
+	req.EqualValues(3, r.Metrics().Snapshot().Handles)
+	req.Equal([]string{"start", "exit"}, hooks)
+}
+
+func TestFuncOnStartFailed(t *testing.T) {
+	req := require.New(t)
+
+	reg := NewRegistry()
+	r := Func("TestFuncOnStartFailed", time.Millisecond, func(ctx context.Context) error {
+		return nil
+	}, WithOnStart(func() error {
+		return errors.New("start failed")
+	}))
+
+	req.EqualError(r.StartIn(reg), "start failed")
+	req.Equal(StateFailed, r.State())
+	req.Equal(0, reg.Len())
+}
@@ -0,0 +1,105 @@
+package runner
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// pauser 记录 Runner 的暂停, 暂停期间循环阻塞在 resumed 上
+type pauser struct {
+	mutex   sync.Mutex
+	since   time.Time     // 暂停开始的时间, 未暂停时为零值
+	resumed chan struct{} // 暂停时创建, 恢复时关闭
+}
+
+// pause 开始暂停, 已暂停时不产生作用
+func (p *pauser) pause(now time.Time) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.resumed != nil {
+		return
+	}
+	p.since = now
+	p.resumed = make(chan struct{})
+}
+
+// resume 结束暂停, 未暂停时不产生作用
+func (p *pauser) resume() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.resumed == nil {
+		return
+	}
+	close(p.resumed)
+	p.since = time.Time{}
+	p.resumed = nil
+}
+
+// wait 返回暂停结束时关闭的 channel, 未暂停时返回 nil
+func (p *pauser) wait() <-chan struct{} {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.resumed
+}
+
+// pausedSince 返回暂停开始的时间, 未暂停时返回零值
+func (p *pauser) pausedSince() time.Time {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.since
+}
+
+// Pause 暂停 Runner, 执行中的 Handle 结束后不再执行 Handle, 直到 Resume.
+// 暂停不会退出循环, 也不会回调 OnExit 与 OnStart. 暂停期间 IsTimeout 始终返回 false,
+// KickAndWait 会等待到 Resume 后的下一次 Handle. Runner 未在运行时不产生作用
+func (r *Runner) Pause() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if err := r.state.transit(StatePaused, nil); err != nil {
+		return
+	}
+	r.pauser.pause(time.Now())
+}
+
+// Resume 恢复暂停的 Runner, 错过的 Handle 会立即执行一次. Runner 未暂停时不产生作用
+func (r *Runner) Resume() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if err := r.state.transit(StateRunning, nil); err != nil {
+		return
+	}
+	// 暂停期间没有 keepalive, 避免恢复后立即被判断为超时
+	r.KeepAlive()
+	r.pauser.resume()
+}
+
+// PausedFor 返回 Runner 到 curTime 为止已经暂停的时间, 未暂停时返回 0
+func (r *Runner) PausedFor(curTime time.Time) time.Duration {
+	since := r.pauser.pausedSince()
+	if since.IsZero() {
+		return 0
+	}
+	return curTime.Sub(since)
+}
+
+// waitResumed 在暂停时阻塞到 Resume, ctx 被取消时返回 false
+func (r *Runner) waitResumed(ctx context.Context) bool {
+	resumed := r.pauser.wait()
+	if resumed == nil {
+		return true
+	}
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-resumed:
+		return true
+	}
+}
@@ -0,0 +1,100 @@
+package runner
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPauseResume(t *testing.T) {
+	req := require.New(t)
+
+	h := &testHandler{}
+	r := NewContextRunner(h, "TestPauseResume", time.Millisecond)
+	r.SetTimeout(10 * time.Millisecond)
+
+	// 未运行时不产生作用
+	r.Pause()
+	req.Equal(StateIdle, r.State())
+
+	req.NoError(r.StartIn(NewRegistry()))
+	defer r.Stop()
+	req.Eventually(func() bool {
+		return h.count() > 0
+	}, time.Second, time.Millisecond)
+
+	r.Pause()
+	req.Equal(StatePaused, r.State())
+	time.Sleep(20 * time.Millisecond)
+	count := h.count()
+	time.Sleep(20 * time.Millisecond)
+	req.Equal(count, h.count())
+
+	// 暂停期间不超时, 并记录暂停的时间
+	req.False(r.IsTimeout(time.Now()))
+	req.True(r.PausedFor(time.Now()) >= 40*time.Millisecond)
+
+	r.Resume()
+	req.Equal(StateRunning, r.State())
+	req.Zero(r.PausedFor(time.Now()))
+	req.Eventually(func() bool {
+		return h.count() > count
+	}, time.Second, time.Millisecond)
+}
+
+func TestStopWhilePaused(t *testing.T) {
+	req := require.New(t)
+
+	r := NewContextRunner(&testHandler{}, "TestStopWhilePaused", time.Millisecond)
+	req.NoError(r.StartIn(NewRegistry()))
+
+	r.Pause()
+	r.Stop()
+	req.Equal(StateStopped, r.State())
+	req.Zero(r.PausedFor(time.Now()))
+
+	// 再次启动时不保留暂停
+	req.NoError(r.StartIn(NewRegistry()))
+	req.Equal(StateRunning, r.State())
+	r.Stop()
+}
+
+func TestPausedDuringLastRun(t *testing.T) {
+	req := require.New(t)
+
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	h := &testHandler{
+		handle: func(ctx context.Context) error {
+			close(entered)
+			<-release
+			return nil
+		},
+	}
+	r := NewContextRunner(h, "TestPausedDuringLastRun", time.Millisecond)
+	r.MaxRuns = 1
+	req.NoError(r.StartIn(NewRegistry()))
+
+	// 在最后一次 Handle 中暂停, 循环正常结束后进入 Stopped
+	<-entered
+	r.Pause()
+	req.Equal(StatePaused, r.State())
+	close(release)
+
+	req.NoError(r.Wait())
+	req.Equal(StateStopped, r.State())
+	req.Zero(r.PausedFor(time.Now()))
+
+	r.Stop()
+	req.Equal(StateStopped, r.State())
+
+	// 可以再次启动
+	release = make(chan struct{})
+	close(release)
+	entered = make(chan struct{})
+	req.NoError(r.StartIn(NewRegistry()))
+	req.NoError(r.Wait())
+	req.Equal(2, h.count())
+}
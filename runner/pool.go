@@ -0,0 +1,512 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Pool 是由多个 worker 并发执行同一个 ContextHandler 的 Runner, 每个 worker 在上一次 Handle 结束后等待 Interval.
+//
+// 所有 worker 共享一个生命周期与 Registry 中的一条记录: OnStart 在所有 worker 启动前回调一次,
+// OnExit 在所有 worker 退出后回调一次. 任一 worker 的 Handle panic 时整个 Pool 以 *PanicError 退出.
+// 与 Runner 相同, Handle 返回 ErrDone 时该 worker 正常退出, 所有 worker 都退出后 Pool 正常结束;
+// SetInterval 调小间隔时, 正在进行的等待会立即按新的间隔缩短. Handler 需要是并发安全的
+type Pool struct {
+	handler ContextHandler
+	name    string
+
+	config  runnerConfig
+	metrics *Metrics // 所有 worker 的汇总指标
+
+	middlewares []Middleware
+	call        HandleFunc // 使用 Middleware 包装后的 HandleContext, 在 Start 时创建
+
+	// 流程控制相关
+	mutex    sync.Mutex
+	size     int
+	workers  []*poolWorker
+	nextID   int
+	starts   int // 成功 Start 的次数
+	wg       sync.WaitGroup
+	ctx      context.Context
+	cancel   context.CancelFunc
+	err      error // 导致本次运行退出的第一个 panic
+	state    stateMachine
+	exit     exitSignal
+	registry *Registry
+}
+
+type poolWorker struct {
+	id             int
+	cancel         context.CancelFunc
+	metrics        *Metrics
+	lastHandleTime atomic.Int64  // UnixNano
+	reconfig       chan struct{} // Interval 修改时通知 worker 重新计算等待时间
+}
+
+func (w *poolWorker) keepAlive(now time.Time) {
+	w.lastHandleTime.Store(now.UnixNano())
+}
+
+// NewPool 创建一个由 size 个 worker 执行 ContextHandler 的 Pool, size 小于 1 时为 1
+func NewPool(handler ContextHandler, name string, size int, interval time.Duration) *Pool {
+	if size < 1 {
+		size = 1
+	}
+	p := &Pool{
+		handler: handler,
+		name:    name,
+		metrics: NewMetrics(),
+		size:    size,
+		config: runnerConfig{
+			interval: interval,
+			timeout:  time.Hour,
+		},
+
+		state: stateMachine{state: StateIdle},
+	}
+	p.metrics.SetInterval(interval)
+	return p
+}
+
+// Start 启动所有 worker, 并记录到 DefaultRegistry. Stop 或失败后可以再次 Start
+func (p *Pool) Start() error {
+	return p.StartIn(DefaultRegistry)
+}
+
+// StartIn 与 Start 相同, 但记录到 reg 中. reg 中存在同名的其他 Runner 时返回 ErrDuplicateName
+func (p *Pool) StartIn(reg *Registry) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if err := p.state.transit(StateStarting, nil); err != nil {
+		return ErrIsRunning
+	}
+
+	// 移除上一次失败后保留的记录, 再加入记录
+	if p.registry != nil {
+		p.registry.Unregister(p)
+	}
+	if err := reg.Register(p); err != nil {
+		_ = p.state.transit(StateFailed, err)
+		return err
+	}
+	p.registry = reg
+
+	if err := p.handler.OnStart(); err != nil {
+		reg.Unregister(p)
+		_ = p.state.transit(StateFailed, err)
+		return err
+	}
+
+	p.call = buildHandle(p.name, p.handler, reg, p.middlewares)
+	p.ctx, p.cancel = context.WithCancel(context.Background())
+	p.err = nil
+	p.exit.reset()
+	for i := 0; i < p.size; i++ {
+		p.spawn()
+	}
+	_ = p.state.transit(StateRunning, nil)
+	p.starts++
+	p.log(slog.LevelInfo, "runner started", attemptAttr(p.starts), slog.Int("workers", p.size))
+
+	go p.run(time.Now())
+
+	return nil
+}
+
+// Stop 取消所有 worker 执行中 Handle 的 ctx, 停止并等待所有 worker 退出
+func (p *Pool) Stop() {
+	_ = p.StopContext(context.Background())
+}
+
+// StopContext 与 Stop 相同, 但最多等待到 ctx 结束.
+// ctx 结束时仍有 worker 未退出则返回 ctx.Err(), Pool 进入 StateAbandoned 并从 Registry 中移除
+func (p *Pool) StopContext(ctx context.Context) error {
+	p.mutex.Lock()
+	if err := p.state.transit(StateStopping, nil); err == nil {
+		p.cancel()
+	} else if state := p.state.get(); state != StateStopping && state != StateAbandoned {
+		// 失败后保留在 Registry 中的记录在 Stop 时清除
+		if state == StateFailed && p.registry != nil {
+			p.registry.Unregister(p)
+		}
+		p.mutex.Unlock()
+		return nil
+	}
+	done := p.exit.channel()
+	registry := p.registry
+	p.mutex.Unlock()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+	}
+
+	if p.state.transit(StateAbandoned, ctx.Err()) == nil {
+		registry.Unregister(p)
+		return ctx.Err()
+	}
+	if p.state.get() == StateAbandoned {
+		return ctx.Err()
+	}
+	return nil
+}
+
+// Resize 将 worker 数量调整为 n, n 小于 1 时为 1.
+// 运行中时立即启动新的 worker 或停止最后启动的多余 worker, 被停止的 worker 执行中 Handle 的 ctx 会被取消.
+// 未运行时在下一次 Start 时生效
+func (p *Pool) Resize(n int) {
+	if n < 1 {
+		n = 1
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.size = n
+	if p.state.get() != StateRunning || p.ctx.Err() != nil {
+		return
+	}
+
+	for len(p.workers) < n {
+		p.spawn()
+	}
+	for len(p.workers) > n {
+		last := len(p.workers) - 1
+		p.workers[last].cancel()
+		p.workers = p.workers[:last]
+	}
+}
+
+// Use 添加包装 Handle 的 Middleware, mws[0] 在最外层. 在下一次 Start 时生效
+func (p *Pool) Use(mws ...Middleware) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.middlewares = append(p.middlewares, mws...)
+}
+
+// Size 返回 worker 的数量
+func (p *Pool) Size() int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.size
+}
+
+// KeepAlive 刷新所有 worker 的 LastHandleTime
+func (p *Pool) KeepAlive() {
+	now := time.Now()
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	for _, w := range p.workers {
+		w.keepAlive(now)
+	}
+}
+
+// LastHandleTime 返回所有 worker 中最早的上一次 Handle 结束的时间, 未运行时返回零值
+func (p *Pool) LastHandleTime() time.Time {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	var oldest int64
+	for _, w := range p.workers {
+		if last := w.lastHandleTime.Load(); oldest == 0 || last < oldest {
+			oldest = last
+		}
+	}
+	if oldest == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, oldest)
+}
+
+// IsTimeout 在任一 worker 超过 Timeout 没有 keepalive 时返回 true
+func (p *Pool) IsTimeout(curTime time.Time) bool {
+	return p.IsTimeoutAfter(curTime, p.Timeout())
+}
+
+// IsTimeoutAfter 与 IsTimeout 相同, 但使用 timeout 代替 Timeout
+func (p *Pool) IsTimeoutAfter(curTime time.Time, timeout time.Duration) bool {
+	if p.state.get() == StateFailed {
+		return false
+	}
+	last := p.LastHandleTime()
+	if last.IsZero() {
+		return false
+	}
+	return curTime.Sub(last) > timeout
+}
+
+func (p *Pool) Name() string {
+	return p.name
+}
+
+// SetInterval 修改每个 worker 两次 Handle 之间的间隔, 从下一次等待开始生效.
+// 间隔变小时, 正在进行的等待会立即按新的间隔缩短
+func (p *Pool) SetInterval(interval time.Duration) {
+	p.config.mutex.Lock()
+	p.config.interval = interval
+	p.config.mutex.Unlock()
+
+	p.metrics.SetInterval(interval)
+	p.mutex.Lock()
+	for _, w := range p.workers {
+		w.metrics.SetInterval(interval)
+		select {
+		case w.reconfig <- struct{}{}:
+		default:
+		}
+	}
+	p.mutex.Unlock()
+}
+
+// Interval 返回每个 worker 两次 Handle 之间的间隔
+func (p *Pool) Interval() time.Duration {
+	p.config.mutex.Lock()
+	defer p.config.mutex.Unlock()
+
+	return p.config.interval
+}
+
+// SetTimeout 修改 IsTimeout 判断阻塞的超时时间, 默认为 1 小时
+func (p *Pool) SetTimeout(timeout time.Duration) {
+	p.config.mutex.Lock()
+	defer p.config.mutex.Unlock()
+
+	p.config.timeout = timeout
+}
+
+// Timeout 返回 IsTimeout 判断阻塞的超时时间
+func (p *Pool) Timeout() time.Duration {
+	p.config.mutex.Lock()
+	defer p.config.mutex.Unlock()
+
+	return p.config.timeout
+}
+
+// SetLogger 修改输出日志的 io.Writer, 日志以 slog.TextHandler 的格式写入, 为 nil 时不输出
+func (p *Pool) SetLogger(logger io.Writer) {
+	p.config.setLogger(logger)
+}
+
+// SetSlog 修改输出结构化日志的 *slog.Logger, 会替换 SetLogger 设置的 io.Writer, 为 nil 时不输出
+func (p *Pool) SetSlog(logger *slog.Logger) {
+	p.config.setSlog(logger)
+}
+
+// Metrics 返回所有 worker 汇总的运行指标
+func (p *Pool) Metrics() *Metrics {
+	return p.metrics
+}
+
+// WorkerMetrics 返回运行中每个 worker 的运行指标, 按 worker 启动顺序排列
+func (p *Pool) WorkerMetrics() []*Metrics {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	metrics := make([]*Metrics, 0, len(p.workers))
+	for _, w := range p.workers {
+		metrics = append(metrics, w.metrics)
+	}
+	return metrics
+}
+
+// State 返回 Pool 当前所处的状态
+func (p *Pool) State() State {
+	return p.state.get()
+}
+
+// Transitions 返回最近的状态转换记录, 按时间从旧到新排列
+func (p *Pool) Transitions() []Transition {
+	return p.state.transitions()
+}
+
+// Done 返回本次运行退出时关闭的 channel
+func (p *Pool) Done() <-chan struct{} {
+	return p.exit.channel()
+}
+
+// Err 返回本次运行退出的原因, Handle panic 时为 *PanicError
+func (p *Pool) Err() error {
+	return p.exit.error()
+}
+
+// Wait 阻塞到本次运行退出, 返回 Err
+func (p *Pool) Wait() error {
+	<-p.Done()
+	return p.Err()
+}
+
+// spawn 启动一个 worker, 需持有 mutex
+func (p *Pool) spawn() {
+	ctx, cancel := context.WithCancel(p.ctx)
+	w := &poolWorker{
+		id:       p.nextID,
+		cancel:   cancel,
+		metrics:  NewMetrics(),
+		reconfig: make(chan struct{}, 1),
+	}
+	w.metrics.SetInterval(p.Interval())
+	w.keepAlive(time.Now())
+	p.nextID++
+	p.workers = append(p.workers, w)
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		defer cancel()
+		p.work(ctx, w)
+	}()
+}
+
+// run 等待所有 worker 退出
+func (p *Pool) run(start time.Time) {
+	p.wg.Wait()
+
+	p.mutex.Lock()
+	err := p.err
+	p.workers = nil
+	p.mutex.Unlock()
+
+	// OnExit 在所有 worker 退出后回调
+	p.handler.OnExit()
+
+	// 与 Runner 相同, 异常退出时保留在 Registry 中
+	if err == nil || p.state.transit(StateFailed, err) != nil {
+		p.registry.Unregister(p)
+		_ = p.state.transit(StateStopped, nil)
+	}
+	if err != nil {
+		p.log(slog.LevelError, "runner failed", durationAttr(time.Since(start)), errorAttr(err))
+	} else {
+		p.log(slog.LevelInfo, "runner stopped", durationAttr(time.Since(start)))
+	}
+	p.exit.close(err)
+}
+
+// work 为单个 worker 的循环, panic 时使整个 Pool 退出
+func (p *Pool) work(ctx context.Context, w *poolWorker) {
+	defer func() {
+		if x := recover(); x != nil {
+			stackBuf := make([]byte, 1024*10)
+			size := runtime.Stack(stackBuf, false)
+			err := &PanicError{Value: x, Stack: stackBuf[0:size]}
+			p.log(slog.LevelError, "runner panic", errorAttr(err), slog.Int("worker", w.id), slog.String("stack", string(stackBuf[0:size])))
+			p.metrics.IncPanic()
+			w.metrics.IncPanic()
+			p.fail(err)
+		}
+	}()
+
+	for {
+		start := time.Now()
+		err := p.call(ctx)
+		if ctx.Err() != nil {
+			// Stop 与 Resize 引起的 ctx 取消不视为失败
+			return
+		}
+		done := errors.Is(err, ErrDone)
+		if done {
+			err = nil
+		}
+
+		d := time.Since(start)
+		p.metrics.ObserveHandle(d, err)
+		w.metrics.ObserveHandle(d, err)
+		var perr *PanicError
+		if errors.As(err, &perr) {
+			// 被 Recover 恢复的 panic 同样计数, 但 Pool 继续运行
+			p.metrics.IncPanic()
+			w.metrics.IncPanic()
+			p.log(slog.LevelError, "runner panic recovered", errorAttr(perr), slog.Int("worker", w.id), slog.String("stack", string(perr.Stack)))
+		}
+		if err != nil {
+			p.log(slog.LevelWarn, "runner handle failed", durationAttr(d), errorAttr(err), slog.Int("worker", w.id))
+		}
+		w.keepAlive(time.Now())
+		if done {
+			p.retire(w)
+			return
+		}
+
+		interval := p.Interval()
+		p.metrics.SetNextDelay(interval)
+		w.metrics.SetNextDelay(interval)
+		if !p.sleep(ctx, w, interval) {
+			return
+		}
+	}
+}
+
+// sleep 等待 d, 等待期间 Interval 被调小时按新的 Interval 缩短. ctx 被取消时返回 false
+func (p *Pool) sleep(ctx context.Context, w *poolWorker, d time.Duration) bool {
+	start := time.Now()
+	deadline := start.Add(d)
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-t.C:
+			return true
+		case <-w.reconfig:
+			next := start.Add(p.Interval())
+			if !next.Before(deadline) {
+				continue
+			}
+			deadline = next
+
+			if !t.Stop() {
+				select {
+				case <-t.C:
+				default:
+				}
+			}
+			now := time.Now()
+			t.Reset(deadline.Sub(now))
+			p.metrics.SetNextDelay(deadline.Sub(now))
+			w.metrics.SetNextDelay(deadline.Sub(now))
+		}
+	}
+}
+
+// retire 移除 Handle 返回 ErrDone 的 worker, 使其不再参与 LastHandleTime 的计算
+func (p *Pool) retire(w *poolWorker) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	for i, worker := range p.workers {
+		if worker == w {
+			p.workers = append(p.workers[:i], p.workers[i+1:]...)
+			return
+		}
+	}
+}
+
+// fail 记录导致 Pool 退出的 err 并停止所有 worker
+func (p *Pool) fail(err error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.err == nil {
+		p.err = err
+	}
+	p.cancel()
+}
+
+func (p *Pool) log(level slog.Level, msg string, attrs ...slog.Attr) {
+	logEvent(p.config.slogger(), level, msg, p.name, attrs...)
+}
@@ -0,0 +1,164 @@
+package runner
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// concurrentHandler 阻塞直到 ctx 结束, 并记录同时执行的 Handle 数量
+type concurrentHandler struct {
+	testHandler
+	running int32
+	started int32
+	exited  int32
+}
+
+func (h *concurrentHandler) HandleContext(ctx context.Context) error {
+	atomic.AddInt32(&h.running, 1)
+	defer atomic.AddInt32(&h.running, -1)
+
+	<-ctx.Done()
+	return nil
+}
+
+func (h *concurrentHandler) OnStart() error {
+	atomic.AddInt32(&h.started, 1)
+	return nil
+}
+
+func (h *concurrentHandler) OnExit() {
+	atomic.AddInt32(&h.exited, 1)
+}
+
+func (h *concurrentHandler) concurrency() int {
+	return int(atomic.LoadInt32(&h.running))
+}
+
+func TestPool(t *testing.T) {
+	req := require.New(t)
+
+	h := &concurrentHandler{}
+	reg := NewRegistry()
+	p := NewPool(h, "TestPool", 3, time.Millisecond)
+	req.NoError(p.StartIn(reg))
+	req.Equal(1, reg.Len())
+	req.ErrorIs(p.Start(), ErrIsRunning)
+
+	req.Eventually(func() bool {
+		return h.concurrency() == 3
+	}, time.Second, time.Millisecond)
+
+	// 扩容与缩容
+	p.Resize(5)
+	req.Eventually(func() bool {
+		return h.concurrency() == 5
+	}, time.Second, time.Millisecond)
+	req.Len(p.WorkerMetrics(), 5)
+
+	p.Resize(2)
+	req.Eventually(func() bool {
+		return h.concurrency() == 2
+	}, time.Second, time.Millisecond)
+	req.Len(p.WorkerMetrics(), 2)
+	req.Equal(StateRunning, p.State())
+
+	p.Stop()
+	req.Equal(0, h.concurrency())
+	req.Equal(StateStopped, p.State())
+	req.Equal(0, reg.Len())
+	req.EqualValues(1, atomic.LoadInt32(&h.started))
+	req.EqualValues(1, atomic.LoadInt32(&h.exited))
+}
+
+func TestPoolMetrics(t *testing.T) {
+	req := require.New(t)
+
+	h := &testHandler{}
+	p := NewPool(h, "TestPoolMetrics", 4, time.Millisecond)
+	req.NoError(p.StartIn(NewRegistry()))
+	defer p.Stop()
+
+	req.Eventually(func() bool {
+		return h.count() > 20
+	}, time.Second, time.Millisecond)
+
+	var total uint64
+	for _, m := range p.WorkerMetrics() {
+		handles := m.Snapshot().Handles
+		req.NotZero(handles)
+		total += handles
+	}
+	req.True(total <= p.Metrics().Snapshot().Handles)
+	req.False(p.IsTimeout(time.Now()))
+}
+
+func TestPoolPanic(t *testing.T) {
+	req := require.New(t)
+
+	var once sync.Once
+	h := &testHandler{
+		handle: func(ctx context.Context) error {
+			once.Do(func() {
+				panic("boom")
+			})
+			<-ctx.Done()
+			return nil
+		},
+	}
+	p := NewPool(h, "TestPoolPanic", 3, time.Millisecond)
+	req.NoError(p.StartIn(NewRegistry()))
+
+	select {
+	case <-p.Done():
+	case <-time.After(time.Second):
+		req.Fail("pool not exited after panic")
+	}
+
+	var perr *PanicError
+	req.ErrorAs(p.Err(), &perr)
+	req.Equal("boom", perr.Value)
+	req.Equal(StateFailed, p.State())
+	req.EqualValues(1, p.Metrics().Snapshot().Panics)
+
+	// 失败后可以再次启动
+	req.NoError(p.StartIn(NewRegistry()))
+	p.Stop()
+	req.Equal(StateStopped, p.State())
+}
+
+func TestPoolSetIntervalAndDone(t *testing.T) {
+	req := require.New(t)
+
+	var calls int32
+	h := &testHandler{
+		handle: func(ctx context.Context) error {
+			// 每个 worker 执行两次后完成
+			if atomic.AddInt32(&calls, 1) > 2 {
+				return ErrDone
+			}
+			return nil
+		},
+	}
+	p := NewPool(h, "TestPoolSetIntervalAndDone", 2, time.Hour)
+	req.NoError(p.StartIn(NewRegistry()))
+	req.Eventually(func() bool {
+		return atomic.LoadInt32(&calls) == 2
+	}, time.Second, time.Millisecond)
+
+	// 调小 Interval 时缩短正在进行的等待
+	p.SetInterval(time.Millisecond)
+	select {
+	case <-p.Done():
+	case <-time.After(time.Second):
+		req.Fail("pool not finished after ErrDone")
+	}
+	req.NoError(p.Err())
+	req.Equal(StateStopped, p.State())
+	req.Equal(int32(4), atomic.LoadInt32(&calls))
+	req.Zero(p.Metrics().Snapshot().Errors)
+}
@@ -0,0 +1,122 @@
+package runner
+
+import (
+	"time"
+)
+
+// MissedTickPolicy 为 FixedRate 下 Handle 耗时过长错过时间点时的处理方式
+type MissedTickPolicy int
+
+const (
+	// SkipMissed 跳过所有错过的时间点, 等待下一个时间点
+	SkipMissed MissedTickPolicy = iota
+	// CatchUpOnce 立即补执行一次, 之后回到正常的时间点
+	CatchUpOnce
+	// CatchUpAll 依次立即补执行所有错过的时间点
+	CatchUpAll
+)
+
+// FixedRate 描述固定频率的调度: 以 Interval 为周期在固定的时间点执行 Handle, 周期不受 Handle 耗时影响.
+//
+// 未设置时 Runner 使用 fixed delay 的调度, 即上一次 Handle 结束后等待 Interval.
+type FixedRate struct {
+	// Missed 为错过时间点时的处理方式
+	Missed MissedTickPolicy
+
+	// Align 为 true 时, 时间点对齐到 Unix 零点 + Phase 的整数倍 Interval, 如 Interval 为 1 分钟时对齐到整分.
+	// 否则以 Start 的时间作为第一个时间点
+	Align bool
+	Phase time.Duration
+}
+
+// rateClock 记录一次运行中 FixedRate 的时间点, 第 k 个时间点为 anchor + k*interval.
+// 只在循环所在 goroutine 中使用
+type rateClock struct {
+	cfg      FixedRate
+	interval time.Duration
+	anchor   time.Time
+	k        int64
+}
+
+func newRateClock(cfg FixedRate, interval time.Duration, now time.Time) *rateClock {
+	c := &rateClock{
+		cfg:      cfg,
+		interval: interval,
+		anchor:   now,
+	}
+	if cfg.Align {
+		c.anchor = alignUp(now, interval, cfg.Phase)
+	}
+	return c
+}
+
+// resumeRateClock 创建从 last 执行过的时间点之后继续的 rateClock, 用于从 Checkpoint 恢复
+func resumeRateClock(cfg FixedRate, interval time.Duration, last time.Time) *rateClock {
+	if cfg.Align {
+		// 第一个时间点为 last 之后的对齐时间
+		return newRateClock(cfg, interval, last.Add(time.Nanosecond))
+	}
+
+	c := newRateClock(cfg, interval, last)
+	c.done()
+	return c
+}
+
+// done 在按时间点执行的 Handle 结束后调用
+func (c *rateClock) done() {
+	c.k++
+}
+
+// wait 返回距离下一个时间点的等待时间, 并按 MissedTickPolicy 处理已错过的时间点.
+// interval 变化时从上一个时间点开始按新的 interval 重新计算
+func (c *rateClock) wait(now time.Time, interval time.Duration) time.Duration {
+	if interval <= 0 {
+		return 0
+	}
+	if interval != c.interval {
+		// 以上一个已执行的时间点作为新的起点
+		if c.k > 0 {
+			c.anchor, c.k = c.anchor.Add(time.Duration(c.k-1)*c.interval), 1
+		}
+		c.interval = interval
+		if c.cfg.Align {
+			c.anchor, c.k = alignUp(now, interval, c.cfg.Phase), 0
+		}
+	}
+
+	due := c.due()
+	if !due.Before(now) {
+		return due.Sub(now)
+	}
+
+	// 错过的时间点数量, 包括 due
+	missed := int64(now.Sub(due)/c.interval) + 1
+	switch c.cfg.Missed {
+	case CatchUpOnce:
+		// 本次补执行代替最后一个错过的时间点
+		c.k += missed - 1
+		return 0
+	case CatchUpAll:
+		return 0
+	default:
+		c.k += missed
+		return c.due().Sub(now)
+	}
+}
+
+func (c *rateClock) due() time.Time {
+	return c.anchor.Add(time.Duration(c.k) * c.interval)
+}
+
+// alignUp 返回不早于 tm 的第一个 Unix 零点 + phase + n*interval 的时间点
+func alignUp(tm time.Time, interval, phase time.Duration) time.Time {
+	base := time.Unix(0, 0).Add(phase % interval)
+	offset := tm.Sub(base) % interval
+	if offset < 0 {
+		offset += interval
+	}
+	if offset == 0 {
+		return tm
+	}
+	return tm.Add(interval - offset)
+}
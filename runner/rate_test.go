@@ -0,0 +1,90 @@
+package runner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAlignUp(t *testing.T) {
+	req := require.New(t)
+
+	tm := time.Date(2023, 4, 1, 10, 30, 15, 0, time.UTC)
+	req.Equal(time.Date(2023, 4, 1, 10, 31, 0, 0, time.UTC), alignUp(tm, time.Minute, 0).UTC())
+	req.Equal(time.Date(2023, 4, 1, 10, 30, 20, 0, time.UTC), alignUp(tm, time.Minute, 20*time.Second).UTC())
+	req.Equal(time.Date(2023, 4, 1, 11, 0, 0, 0, time.UTC), alignUp(tm, time.Hour, 0).UTC())
+
+	onMinute := time.Date(2023, 4, 1, 10, 30, 0, 0, time.UTC)
+	req.Equal(onMinute, alignUp(onMinute, time.Minute, 0).UTC())
+}
+
+func TestRateClockMissedTicks(t *testing.T) {
+	start := time.Date(2023, 4, 1, 10, 0, 0, 0, time.UTC)
+	interval := 10 * time.Second
+
+	cases := map[string]struct {
+		policy MissedTickPolicy
+		// 第一次 Handle 耗时 35s 后连续三次的等待时间, 之后的 Handle 不耗时
+		expect []time.Duration
+	}{
+		"skip": {
+			policy: SkipMissed,
+			expect: []time.Duration{5 * time.Second, 10 * time.Second, 10 * time.Second},
+		},
+		"catch up once": {
+			policy: CatchUpOnce,
+			expect: []time.Duration{0, 5 * time.Second, 10 * time.Second},
+		},
+		"catch up all": {
+			policy: CatchUpAll,
+			expect: []time.Duration{0, 0, 0, 5 * time.Second},
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			req := require.New(t)
+
+			clock := newRateClock(FixedRate{Missed: c.policy}, interval, start)
+			req.Equal(time.Duration(0), clock.wait(start, interval))
+
+			now := start.Add(35 * time.Second)
+			for _, expect := range c.expect {
+				clock.done()
+				wait := clock.wait(now, interval)
+				req.Equal(expect, wait)
+				now = now.Add(wait)
+			}
+		})
+	}
+}
+
+func TestRateClockAlign(t *testing.T) {
+	req := require.New(t)
+
+	start := time.Date(2023, 4, 1, 10, 0, 45, 0, time.UTC)
+	clock := newRateClock(FixedRate{Align: true}, time.Minute, start)
+	req.Equal(15*time.Second, clock.wait(start, time.Minute))
+
+	// Handle 耗时不影响下一个时间点
+	clock.done()
+	req.Equal(50*time.Second, clock.wait(start.Add(25*time.Second), time.Minute))
+
+	// interval 变化时重新对齐
+	req.Equal(20*time.Second, clock.wait(start.Add(25*time.Second), 30*time.Second))
+}
+
+func TestFixedRateRunner(t *testing.T) {
+	req := require.New(t)
+
+	h := &testHandler{}
+	r := NewContextRunner(h, "TestFixedRateRunner", 10*time.Millisecond)
+	r.FixedRate = &FixedRate{Missed: SkipMissed}
+	req.NoError(r.StartIn(NewRegistry()))
+	defer r.Stop()
+
+	req.Eventually(func() bool {
+		return h.count() >= 3
+	}, time.Second, time.Millisecond)
+}
@@ -0,0 +1,215 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrDuplicateName 表示 Registry 中已存在同名的其他 Runner
+	ErrDuplicateName = errors.New("duplicate runner name")
+
+	// DefaultRegistry 为 Runner.Start 默认使用的 Registry
+	DefaultRegistry = NewRegistry()
+
+	// RunningRunners 记录所有通过 Start 启动且运行中的 Runner
+	//
+	// Deprecated: 使用 DefaultRegistry
+	RunningRunners = DefaultRegistry
+)
+
+// namespaceSeparator 为 namespace 与 name 之间的分隔符
+const namespaceSeparator = "/"
+
+// Registry 记录运行中的 Runner, 同一 Registry 中 name 不可重复.
+// 异常退出(StateFailed)的 Runner 会保留在 Registry 中, 直到其再次 Start 或 Stop.
+//
+// Namespace 返回的子 Registry 与父 Registry 共享存储, 其中的 Runner 以 "namespace/name" 记录,
+// 因此不同 namespace 中可以存在同名的 Runner.
+type Registry struct {
+	namespace string
+	store     *registryStore
+}
+
+type registryStore struct {
+	mutex       sync.RWMutex
+	runners     map[string]IRunner // namespace/name : IRunner
+	middlewares []Middleware
+}
+
+// NewRegistry 创建一个空的 Registry
+func NewRegistry() *Registry {
+	return &Registry{
+		store: &registryStore{
+			runners: make(map[string]IRunner),
+		},
+	}
+}
+
+// Namespace 返回 namespace 为 ns 的子 Registry
+func (reg *Registry) Namespace(ns string) *Registry {
+	return &Registry{
+		namespace: reg.key(ns),
+		store:     reg.store,
+	}
+}
+
+// Use 添加所有 namespace 中的 Runner 共用的 Middleware, 在 Runner 自身的 Middleware 外层执行.
+// 只对之后启动的 Runner 生效
+func (reg *Registry) Use(mws ...Middleware) {
+	reg.store.mutex.Lock()
+	defer reg.store.mutex.Unlock()
+
+	reg.store.middlewares = append(reg.store.middlewares, mws...)
+}
+
+func (reg *Registry) middlewares() []Middleware {
+	reg.store.mutex.RLock()
+	defer reg.store.mutex.RUnlock()
+
+	return append([]Middleware(nil), reg.store.middlewares...)
+}
+
+// Register 记录 runner, 已存在同名的其他 Runner 时返回 ErrDuplicateName
+func (reg *Registry) Register(runner IRunner) error {
+	key := reg.key(runner.Name())
+
+	reg.store.mutex.Lock()
+	defer reg.store.mutex.Unlock()
+
+	if exist, ok := reg.store.runners[key]; ok && exist != runner {
+		return ErrDuplicateName
+	}
+	reg.store.runners[key] = runner
+
+	return nil
+}
+
+// Unregister 移除 runner, 同名的其他 Runner 不会被移除
+func (reg *Registry) Unregister(runner IRunner) {
+	key := reg.key(runner.Name())
+
+	reg.store.mutex.Lock()
+	defer reg.store.mutex.Unlock()
+
+	if exist, ok := reg.store.runners[key]; ok && exist == runner {
+		delete(reg.store.runners, key)
+	}
+}
+
+// Lookup 返回当前 namespace 中名为 name 的 Runner
+func (reg *Registry) Lookup(name string) (IRunner, bool) {
+	reg.store.mutex.RLock()
+	defer reg.store.mutex.RUnlock()
+
+	runner, ok := reg.store.runners[reg.key(name)]
+	return runner, ok
+}
+
+// Resolve 查找名为 name 的 Runner, 并返回其所在 namespace 的 Registry.
+// name 为相对于当前 namespace 的名称, 即 Snapshot 中的 key
+func (reg *Registry) Resolve(name string) (*Registry, IRunner, bool) {
+	runner, ok := reg.Lookup(name)
+	if !ok {
+		return nil, nil, false
+	}
+	return reg.scopeOf(name, runner), runner, true
+}
+
+// Snapshot 返回当前 namespace 及其子 namespace 中所有 Runner 的拷贝.
+// key 为相对于当前 namespace 的名称, 子 namespace 中的 Runner 以 "namespace/name" 表示
+func (reg *Registry) Snapshot() map[string]IRunner {
+	reg.store.mutex.RLock()
+	defer reg.store.mutex.RUnlock()
+
+	snapshot := make(map[string]IRunner)
+	for key, runner := range reg.store.runners {
+		if name, ok := reg.relative(key); ok {
+			snapshot[name] = runner
+		}
+	}
+	return snapshot
+}
+
+// List 返回当前 namespace 及其子 namespace 中所有 Runner, 按名称排序
+func (reg *Registry) List() []IRunner {
+	snapshot := reg.Snapshot()
+
+	names := make([]string, 0, len(snapshot))
+	for name := range snapshot {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	runners := make([]IRunner, 0, len(names))
+	for _, name := range names {
+		runners = append(runners, snapshot[name])
+	}
+	return runners
+}
+
+// Range 按名称顺序遍历 Snapshot, f 返回 false 时停止遍历
+func (reg *Registry) Range(f func(name string, runner IRunner) bool) {
+	snapshot := reg.Snapshot()
+
+	names := make([]string, 0, len(snapshot))
+	for name := range snapshot {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if !f(name, snapshot[name]) {
+			return
+		}
+	}
+}
+
+// Len 返回当前 namespace 及其子 namespace 中 Runner 的数量
+func (reg *Registry) Len() int {
+	return len(reg.Snapshot())
+}
+
+// CheckaliveForever 用于周期性对 Registry 中所有 runner keepalive
+//
+// Deprecated: 使用可以停止的 Watchdog
+func (reg *Registry) CheckaliveForever(interval time.Duration, handleTimeout func(name string, runner IRunner, curTime time.Time)) {
+	w := NewWatchdog(reg, interval, func(report TimeoutReport) {
+		handleTimeout(report.Name, report.Runner, report.Time)
+	})
+	w.Run(context.Background())
+}
+
+// scopeOf 返回 runner 所在 namespace 的 Registry, name 为 runner 相对于当前 namespace 的名称
+func (reg *Registry) scopeOf(name string, runner IRunner) *Registry {
+	if name == runner.Name() {
+		return reg
+	}
+	ns := strings.TrimSuffix(name, namespaceSeparator+runner.Name())
+	return reg.Namespace(ns)
+}
+
+// key 返回 name 在 store 中的完整名称
+func (reg *Registry) key(name string) string {
+	if reg.namespace == "" {
+		return name
+	}
+	return reg.namespace + namespaceSeparator + name
+}
+
+// relative 返回 key 相对于当前 namespace 的名称, 不属于当前 namespace 时返回 false
+func (reg *Registry) relative(key string) (string, bool) {
+	if reg.namespace == "" {
+		return key, true
+	}
+
+	prefix := reg.namespace + namespaceSeparator
+	if !strings.HasPrefix(key, prefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(key, prefix), true
+}
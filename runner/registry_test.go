@@ -0,0 +1,92 @@
+package runner
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistryDuplicateName(t *testing.T) {
+	req := require.New(t)
+
+	reg := NewRegistry()
+	r1 := NewContextRunner(&testHandler{}, "dup", time.Hour)
+	r2 := NewContextRunner(&testHandler{}, "dup", time.Hour)
+
+	req.NoError(r1.StartIn(reg))
+	req.ErrorIs(r2.StartIn(reg), ErrDuplicateName)
+	req.Equal(StateFailed, r2.State())
+
+	found, ok := reg.Lookup("dup")
+	req.True(ok)
+	req.Same(r1, found)
+
+	// 同名 Runner 停止时不会移除其他 Runner 的记录
+	r2.Stop()
+	req.Equal(1, reg.Len())
+
+	r1.Stop()
+	req.Equal(0, reg.Len())
+	req.NoError(r2.StartIn(reg))
+	r2.Stop()
+}
+
+func TestRegistryKeepsFailed(t *testing.T) {
+	req := require.New(t)
+
+	h := &testHandler{
+		handle: func(ctx context.Context) error {
+			panic("boom")
+		},
+	}
+	reg := NewRegistry()
+	r := NewContextRunner(h, "TestRegistryKeepsFailed", time.Hour)
+	req.NoError(r.StartIn(reg))
+	<-r.Done()
+
+	// panic 后保留在 Registry 中, 且不会被判断为超时
+	req.Equal(StateFailed, r.State())
+	found, ok := reg.Lookup(r.Name())
+	req.True(ok)
+	req.Same(r, found)
+	req.False(r.IsTimeout(time.Now().Add(2 * time.Hour)))
+
+	// 再次启动到其他 Registry 时移除原来的记录
+	other := NewRegistry()
+	req.NoError(r.StartIn(other))
+	req.Equal(0, reg.Len())
+	<-r.Done()
+	req.Equal(1, other.Len())
+
+	// Stop 清除失败后保留的记录
+	r.Stop()
+	req.Equal(StateFailed, r.State())
+	req.Equal(0, other.Len())
+}
+
+func TestRegistryNamespace(t *testing.T) {
+	req := require.New(t)
+
+	reg := NewRegistry()
+	a := reg.Namespace("a")
+	b := reg.Namespace("b")
+
+	ra := NewContextRunner(&testHandler{}, "worker", time.Hour)
+	rb := NewContextRunner(&testHandler{}, "worker", time.Hour)
+	req.NoError(ra.StartIn(a))
+	req.NoError(rb.StartIn(b))
+	defer ra.Stop()
+	defer rb.Stop()
+
+	found, ok := a.Lookup("worker")
+	req.True(ok)
+	req.Same(ra, found)
+	_, ok = reg.Lookup("worker")
+	req.False(ok)
+
+	req.Equal(map[string]IRunner{"worker": rb}, b.Snapshot())
+	req.Equal(map[string]IRunner{"a/worker": ra, "b/worker": rb}, reg.Snapshot())
+	req.Equal([]IRunner{ra, rb}, reg.List())
+}
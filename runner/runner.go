@@ -1,20 +1,31 @@
+// Package runner 提供封装了永久循环的 goroutine 对象 Runner, 以及管理一组 Runner 的 Registry, Watchdog, Supervisor 与 Group.
+//
+// Handle 发生 panic 时, Runner 会恢复 panic 并以 *PanicError 退出, 进入 StateFailed,
+// 而不是使整个进程崩溃. 未设置日志时 panic 会输出到 slog.Default().
+// StateFailed 的 Runner 保留在 Registry 中, 直到再次 Start 或 Stop, 以便查看其错误.
+// 需要在 panic 后重启时使用 Supervisor, 或通过 Done 与 Err 得知退出的原因.
 package runner
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"runtime"
 	"sync"
 	"sync/atomic"
 	"time"
+
+	"github.com/KanShiori/kit/eventbus"
+	"github.com/KanShiori/kit/timer"
 )
 
 var (
 	ErrIsRunning = errors.New("runner is running")
 
-	// RunningRunners 记录所有运行中的 Runner, name : *Runner
-	RunningRunners sync.Map = sync.Map{}
+	// ErrDone 由 Handle 返回, 表示任务已经完成, Runner 会正常结束循环. 可以被包装后返回
+	ErrDone = errors.New("runner done")
 )
 
 // IRunner 包含 Runner 提供给外部的接口, 用于接口的继承
@@ -35,85 +46,242 @@ type IRunner interface {
 	Name() string
 }
 
+// Supervisable 为可以被 Supervisor 管理的 IRunner, Supervisor 通过 Done 与 Err 得知其异常退出
+type Supervisable interface {
+	IRunner
+
+	// Done 返回本次运行退出时关闭的 channel
+	Done() <-chan struct{}
+
+	// Err 返回本次运行退出的原因, 正常 Stop 时为 nil
+	Err() error
+}
+
+// PanicError 为 Handle 发生 panic 时 Runner 退出的原因
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// Overrun 描述一次执行时间超过 HandleTimeout 的 Handle
+type Overrun struct {
+	Name          string
+	HandleTimeout time.Duration
+	Elapsed       time.Duration
+
+	// Err 为该次 Handle 返回的 error, 响应了 ctx 的 Handle 通常为 context.DeadlineExceeded
+	Err error
+}
+
 // Runner 是封装了永久循环的 goroutine 对象
 type Runner struct {
-	handler Handler
+	handler ContextHandler
 	name    string
 
-	Timeout  time.Duration
-	Interval time.Duration
-	Logger   io.Writer
+	// config 为运行中可以通过 SetInterval, SetTimeout, SetLogger 与 SetSlog 修改的配置
+	config   runnerConfig
+	reconfig chan struct{} // 配置修改时通知循环重新计算等待时间
+
+	// 以下导出的配置在 Start 时复制到 settings, 运行中修改不影响本次运行, 在下一次 Start 时生效
+
+	// Backoff 不为 nil 时, Handle 返回 error 后按其延迟下一次执行, 在 Start 时读取
+	Backoff *Backoff
+
+	// Timer 不为 nil 时, 只在 Timer.ClockIn 允许时执行 Handle, 否则等待到其给出的 next 再检查.
+	// 两次 Handle 之间仍至少间隔 Interval. 在 Start 时读取
+	Timer timer.Timer
+
+	// FixedRate 不为 nil 时使用固定频率调度, 否则在上一次 Handle 结束后等待 Interval. 在 Start 时读取
+	FixedRate *FixedRate
+
+	// MaxRuns 大于 0 时, 执行 MaxRuns 次 Handle 后正常结束循环. 在 Start 时读取
+	MaxRuns int
+
+	// RunUntil 不为零值时, 到达该时间后不再执行 Handle 并正常结束循环. 在 Start 时读取
+	RunUntil time.Time
+
+	// HandleTimeout 大于 0 时, 作为每次 HandleContext 的 ctx 的 deadline. 在 Start 时读取.
+	// Handle 执行超过 HandleTimeout 时视为 overrun, 记录到 Metrics 并回调 OnOverrun
+	HandleTimeout time.Duration
+
+	// OnOverrun 不为 nil 时在每次 overrun 后于循环所在 goroutine 中回调, 不应阻塞. 在 Start 时读取
+	OnOverrun func(overrun Overrun)
+
+	// Lease 不为 nil 时, 只在持有 Lease 期间执行 Handle, 用于多个进程中只有一个执行. 在 Start 时读取.
+	// 运行中每隔 LeaseInterval 续约, 失去 Lease 时取消执行中 Handle 的 ctx, 并每隔 LeaseInterval 尝试重新获取.
+	// LeaseInterval 为 0 时使用 Interval
+	Lease         Lease
+	LeaseInterval time.Duration
+
+	// Checkpoints 不为 nil 时, 以 Runner 名称持久化每次 Handle 的开始与结束时间. 在 Start 时读取.
+	// 每次 Handle 结束后在循环所在 goroutine 中保存一次, 因 Stop 取消而返回 context.Canceled 的 Handle 不保存.
+	// 启动时从中恢复: 第一次 Handle 等待到上一次结束后的 Interval, FixedRate 从上一次的时间点继续,
+	// Timer 实现了 ResetAs 时以上一次 Handle 开始的时间重置
+	Checkpoints CheckpointStore
+
+	// Bus 不为 nil 时将生命周期事件发布到 Bus, topic 见 TopicStarted 等常量. 在 Start 时读取
+	Bus eventbus.Bus
+
+	// SkipNextOnOverrun 为 true 时, overrun 后跳过下一次按计划执行的 Handle, Kick 触发的执行不受影响. 在 Start 时读取
+	SkipNextOnOverrun bool
+
+	middlewares []Middleware
+	call        HandleFunc                  // 使用 Middleware 包装后的 HandleContext, 在 Start 时创建
+	settings    atomic.Pointer[runSettings] // 在 Start 时复制的配置, 未启动过时为 nil
 
 	lastHandleTime *atomic.Value // time.Time
+	result         handleResult
+	checkpoint     Checkpoint // 只在循环所在 goroutine 中访问
+	metrics        *Metrics
+	kicker         *kicker
+	pauser         pauser
+
+	starts      atomic.Int64  // 成功 Start 的次数
+	goid        atomic.Uint64 // 循环所在 goroutine 的 id, 未运行时为 0
+	handleStart atomic.Int64  // 执行中的 Handle 的开始时间(UnixNano), 未执行时为 0
 
 	// 流程控制相关
-	mutex   sync.Mutex
-	running bool
-	stopCh  chan struct{}
-	wg      sync.WaitGroup
+	mutex    sync.Mutex
+	state    stateMachine
+	exit     exitSignal
+	registry *Registry
+	cancel   context.CancelFunc
+}
+
+// NewRunner 创建一个执行 Handler 的 Runner
+func NewRunner(handler Handler, name string, interval time.Duration, opts ...Option) *Runner {
+	return NewContextRunner(AdaptHandler(handler), name, interval, opts...)
+}
+
+// NewErrorRunner 创建一个执行 ErrorHandler 的 Runner
+func NewErrorRunner(handler ErrorHandler, name string, interval time.Duration, opts ...Option) *Runner {
+	return NewContextRunner(AdaptErrorHandler(handler), name, interval, opts...)
 }
 
-func NewRunner(handler Handler, name string, interval time.Duration) *Runner {
+// NewContextRunner 创建一个执行 ContextHandler 的 Runner, Stop 时会取消 HandleContext 的 ctx
+func NewContextRunner(handler ContextHandler, name string, interval time.Duration, opts ...Option) *Runner {
 	r := &Runner{
 		handler:        handler,
 		name:           name,
 		mutex:          sync.Mutex{},
 		lastHandleTime: &atomic.Value{},
-		Interval:       interval,
-		Timeout:        time.Hour,
-
-		running: false,
-		stopCh:  make(chan struct{}),
-		wg:      sync.WaitGroup{},
+		metrics:        NewMetrics(),
+		kicker:         newKicker(),
+		reconfig:       make(chan struct{}, 1),
+		config: runnerConfig{
+			interval: interval,
+			timeout:  time.Hour,
+		},
+
+		state: stateMachine{state: StateIdle},
 	}
 	r.lastHandleTime.Store(time.Now())
+	r.metrics.SetInterval(interval)
+	for _, opt := range opts {
+		opt(r)
+	}
 
 	return r
 }
 
-// Start 开始进行永久循环执行 handle 方法
+// Start 开始进行永久循环执行 handle 方法, 并记录到 DefaultRegistry. Stop 或失败后可以再次 Start
 func (r *Runner) Start() error {
+	return r.StartIn(DefaultRegistry)
+}
+
+// StartIn 与 Start 相同, 但记录到 reg 中. reg 中存在同名的其他 Runner 时返回 ErrDuplicateName
+func (r *Runner) StartIn(reg *Registry) error {
 
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
-	if r.running {
+	if err := r.state.transit(StateStarting, nil); err != nil {
 		return ErrIsRunning
 	}
 
+	// 移除上一次失败后保留的记录, 再加入记录
+	if r.registry != nil {
+		r.registry.Unregister(r)
+	}
+	if err := reg.Register(r); err != nil {
+		_ = r.state.transit(StateFailed, err)
+		return err
+	}
+	r.registry = reg
+
 	err := r.handler.OnStart()
 	if err != nil {
+		reg.Unregister(r)
+		_ = r.state.transit(StateFailed, err)
 		return err
 	}
 
-	r.running = true
-	go r.run()
-
-	// 加入记录
-	RunningRunners.Store(r.name, r)
+	r.call = buildHandle(r.name, r.handler, reg, r.middlewares)
+	r.settings.Store(r.snapshot())
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	r.exit.reset()
+	r.kicker.open()
+	r.pauser.resume()
+	r.KeepAlive()
+	_ = r.state.transit(StateRunning, nil)
+	attempt := int(r.starts.Add(1))
+	r.log(slog.LevelInfo, "runner started", attemptAttr(attempt))
+	r.publish(TopicStarted, &StartedEvent{Name: r.name, Time: time.Now(), Attempt: attempt})
+
+	go r.run(ctx)
 
 	return nil
 }
 
-// Stop 调用 OnExit 回调, 停止并等待 runner 的退出
+// Stop 取消执行中 Handle 的 ctx, 停止并等待 runner 的退出. OnExit 在循环退出后回调.
+// 已失败的 Runner 调用 Stop 时会清除其在 Registry 中保留的记录
 func (r *Runner) Stop() {
-	r.mutex.Lock()
-	defer r.mutex.Unlock()
+	_ = r.StopContext(context.Background())
+}
 
-	if !r.running {
-		return
+// StopContext 与 Stop 相同, 但最多等待到 ctx 结束.
+//
+// ctx 结束时循环仍未退出则返回 ctx.Err(), Runner 进入 StateAbandoned 并从 Registry 中移除,
+// 循环最终退出后进入 StateStopped. 等待期间不会阻塞 IsTimeout, Name 与 Metrics 等只读方法
+func (r *Runner) StopContext(ctx context.Context) error {
+	r.mutex.Lock()
+	if err := r.state.transit(StateStopping, nil); err == nil {
+		r.cancel()
+	} else if state := r.state.get(); state != StateStopping && state != StateAbandoned {
+		// 失败后保留在 Registry 中的记录在 Stop 时清除
+		if state == StateFailed && r.registry != nil {
+			r.registry.Unregister(r)
+		}
+		r.mutex.Unlock()
+		return nil
 	}
-
-	r.handler.OnExit()
-
-	close(r.stopCh)
+	done := r.exit.channel()
+	registry := r.registry
+	r.mutex.Unlock()
 
 	// 等待 run 退出
-	r.wg.Wait()
-	r.running = false
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+	}
 
-	// 清除记录
-	RunningRunners.Delete(r.name)
+	if r.state.transit(StateAbandoned, ctx.Err()) == nil {
+		// 清除记录
+		registry.Unregister(r)
+		return ctx.Err()
+	}
+	if r.state.get() == StateAbandoned {
+		return ctx.Err()
+	}
+
+	// 循环恰好已经退出
+	return nil
 }
 
 // KeepAlive 刷新 LastHandleTime. 默认会在每次 handle 执行后执行
@@ -126,53 +294,627 @@ func (r *Runner) LastHandleTime() time.Time {
 	return r.lastHandleTime.Load().(time.Time)
 }
 
-// IsTimeout 用于检查 Runner 是否阻塞
+// IsTimeout 用于检查 Runner 是否阻塞, 暂停中与已失败的 Runner 不会超时
 func (r *Runner) IsTimeout(curTime time.Time) bool {
-	r.mutex.Lock()
-	defer r.mutex.Unlock()
+	return r.IsTimeoutAfter(curTime, r.Timeout())
+}
 
+// IsTimeoutAfter 与 IsTimeout 相同, 但使用 timeout 代替 Timeout, 用于 Watchdog 的单独超时设置
+func (r *Runner) IsTimeoutAfter(curTime time.Time, timeout time.Duration) bool {
+	if !r.pauser.pausedSince().IsZero() || r.state.get() == StateFailed {
+		return false
+	}
 	last := r.lastHandleTime.Load().(time.Time)
 
-	return curTime.Sub(last) > r.Timeout
+	return curTime.Sub(last) > timeout
 }
 
 func (r *Runner) Name() string {
 	return r.name
 }
 
-func (r *Runner) run() {
+// Use 添加包装 Handle 的 Middleware, mws[0] 在最外层. 在下一次 Start 时生效
+func (r *Runner) Use(mws ...Middleware) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.middlewares = append(r.middlewares, mws...)
+}
+
+// SetInterval 修改两次 Handle 之间的间隔, 从下一次等待开始生效.
+// 间隔变小时, 正在进行的等待会立即按新的间隔缩短
+func (r *Runner) SetInterval(interval time.Duration) {
+	r.config.mutex.Lock()
+	r.config.interval = interval
+	r.config.mutex.Unlock()
+	r.metrics.SetInterval(interval)
+
+	select {
+	case r.reconfig <- struct{}{}:
+	default:
+	}
+}
+
+// Interval 返回两次 Handle 之间的间隔
+func (r *Runner) Interval() time.Duration {
+	r.config.mutex.Lock()
+	defer r.config.mutex.Unlock()
+
+	return r.config.interval
+}
+
+// SetTimeout 修改 IsTimeout 判断阻塞的超时时间, 默认为 1 小时
+func (r *Runner) SetTimeout(timeout time.Duration) {
+	r.config.mutex.Lock()
+	defer r.config.mutex.Unlock()
+
+	r.config.timeout = timeout
+}
+
+// Timeout 返回 IsTimeout 判断阻塞的超时时间
+func (r *Runner) Timeout() time.Duration {
+	r.config.mutex.Lock()
+	defer r.config.mutex.Unlock()
+
+	return r.config.timeout
+}
+
+// SetLogger 修改输出日志的 io.Writer, 日志以 slog.TextHandler 的格式写入, 为 nil 时不输出
+func (r *Runner) SetLogger(logger io.Writer) {
+	r.config.setLogger(logger)
+}
+
+// Logger 返回通过 SetLogger 设置的 io.Writer
+func (r *Runner) Logger() io.Writer {
+	r.config.mutex.Lock()
+	defer r.config.mutex.Unlock()
+
+	return r.config.logger
+}
+
+// SetSlog 修改输出结构化日志的 *slog.Logger, 会替换 SetLogger 设置的 io.Writer, 为 nil 时不输出.
+// 日志包含启动, 停止, panic, Handle 失败, 退避与 overrun, 属性名见 LogKeyRunner 等常量
+func (r *Runner) SetSlog(logger *slog.Logger) {
+	r.config.setSlog(logger)
+}
+
+// Slog 返回输出结构化日志的 *slog.Logger, 未设置时返回 nil
+func (r *Runner) Slog() *slog.Logger {
+	return r.config.slogger()
+}
+
+// LastError 返回最近一次失败的 Handle 返回的 error
+func (r *Runner) LastError() error {
+	r.result.mutex.Lock()
+	defer r.result.mutex.Unlock()
+
+	return r.result.lastErr
+}
+
+// ConsecutiveFailures 返回 Handle 连续失败的次数, Handle 成功后清零
+func (r *Runner) ConsecutiveFailures() int {
+	r.result.mutex.Lock()
+	defer r.result.mutex.Unlock()
+
+	return r.result.failures
+}
+
+// HandleElapsed 返回执行中的 Handle 已经执行的时间, 未在执行 Handle 时返回 0
+func (r *Runner) HandleElapsed(curTime time.Time) time.Duration {
+	start := r.handleStart.Load()
+	if start == 0 {
+		return 0
+	}
+	return curTime.Sub(time.Unix(0, start))
+}
+
+// Stack 返回循环所在 goroutine 的调用栈, 用于定位阻塞的 Handle. 未运行时返回 nil
+func (r *Runner) Stack() []byte {
+	return goroutineStack(r.goid.Load())
+}
+
+// Metrics 返回 Runner 的运行指标
+func (r *Runner) Metrics() *Metrics {
+	return r.metrics
+}
+
+// State 返回 Runner 当前所处的状态
+func (r *Runner) State() State {
+	return r.state.get()
+}
+
+// Transitions 返回最近的状态转换记录, 按时间从旧到新排列
+func (r *Runner) Transitions() []Transition {
+	return r.state.transitions()
+}
+
+// Done 返回本次运行退出时关闭的 channel. 未启动时返回的 channel 会在下一次运行退出时关闭
+func (r *Runner) Done() <-chan struct{} {
+	return r.exit.channel()
+}
+
+// Err 返回本次运行退出的原因, Handle panic 时为 *PanicError
+func (r *Runner) Err() error {
+	return r.exit.error()
+}
+
+// Wait 阻塞到本次运行退出, 返回 Err. 用于等待设置了 MaxRuns, RunUntil 或 Handle 返回 ErrDone 的 Runner 完成
+func (r *Runner) Wait() error {
+	<-r.Done()
+	return r.Err()
+}
+
+func (r *Runner) run(ctx context.Context) {
+	r.goid.Store(goroutineID())
+
+	start := time.Now()
+	var err error
+	defer func() {
+		r.cancel()
+		r.goid.Store(0)
+		r.kicker.close()
+		r.pauser.resume()
+
+		// OnExit 在循环真正退出后回调
+		r.handler.OnExit()
+
+		// 异常退出时进入 Failed 并保留在 Registry 中, 以便查看与再次 Start.
+		// Stop 引起的退出与正常结束清除记录并进入 Stopped
+		if err == nil || r.state.transit(StateFailed, err) != nil {
+			r.registry.Unregister(r)
+			_ = r.state.transit(StateStopped, nil)
+		}
+		uptime := time.Since(start)
+		if err != nil {
+			r.log(slog.LevelError, "runner failed", durationAttr(uptime), errorAttr(err))
+		} else {
+			r.log(slog.LevelInfo, "runner stopped", durationAttr(uptime))
+		}
+		r.publish(TopicStopped, &StoppedEvent{Name: r.name, Time: time.Now(), Uptime: uptime, Err: err})
+		r.exit.close(err)
+	}()
+
+	err = r.loop(ctx)
+}
+
+// loop 循环执行 handle, panic 会被恢复并作为 *PanicError 返回
+func (r *Runner) loop(ctx context.Context) (err error) {
 	defer func() {
 		if x := recover(); x != nil {
 			stackBuf := make([]byte, 1024*10)
-			size := runtime.Stack(stackBuf, true)
-			msg := fmt.Sprintf("catch panic in Runner.run {panic=%s, name=%s}: %s", x, r.name, stackBuf[0:size])
-
-			if r.Logger != nil {
-				_, _ = r.Logger.Write([]byte(msg))
+			size := runtime.Stack(stackBuf, false)
+			perr := &PanicError{Value: x, Stack: stackBuf[0:size]}
+			// 未设置日志时同样输出 panic, 避免 Runner 无声地退出
+			logger := r.Slog()
+			if logger == nil {
+				logger = slog.Default()
 			}
-			panic(x)
+			logEvent(logger, slog.LevelError, "runner panic", r.name, errorAttr(perr), slog.String("stack", string(stackBuf[0:size])))
+			r.metrics.IncPanic()
+			r.publish(TopicPanicked, &PanickedEvent{Name: r.name, Time: time.Now(), Panic: perr})
+			err = perr
 		}
 	}()
 
-	r.wg.Add(1)
-	defer r.wg.Done()
+	// do while, 第一次不等待. FixedRate 对齐时等待到第一个时间点, 存在 Checkpoint 时从上一次的执行继续
+	var rate *rateClock
+	var wait time.Duration
+	settings := r.settings.Load()
+	last, restored := r.restore()
+	if settings.fixedRate != nil {
+		now, interval := time.Now(), r.Interval()
+		if restored {
+			rate = resumeRateClock(*settings.fixedRate, interval, last.LastStart)
+		} else {
+			rate = newRateClock(*settings.fixedRate, interval, now)
+		}
+		wait = rate.wait(now, interval)
+	} else if restored {
+		wait = time.Until(last.lastEnd().Add(r.Interval()))
+	}
 
-	// do while
-	select {
-	case <-r.stopCh:
-		return
-	default:
-		r.handler.Handle()
-		r.KeepAlive()
+	var lease *leaseKeeper
+	if settings.lease != nil {
+		lease = &leaseKeeper{runner: r, lease: settings.lease}
+		defer lease.release()
+		go lease.keep(ctx, r.leaseInterval())
 	}
 
+	var runs int
+	var skip bool // 跳过下一次按计划执行的 Handle
 	for {
+		// 不等待超过 RunUntil
+		if !settings.runUntil.IsZero() {
+			if left := time.Until(settings.runUntil); left < wait {
+				wait = left
+			}
+		}
+
+		kicked, ok := r.sleep(ctx, wait, rate)
+		if !ok || !r.waitResumed(ctx) {
+			return nil
+		}
+		if !settings.runUntil.IsZero() && !time.Now().Before(settings.runUntil) {
+			return nil
+		}
+
+		if skip && !kicked {
+			skip = false
+			if rate != nil {
+				rate.done()
+			}
+			wait = r.delay(rate)
+			continue
+		}
+
+		// 未持有 Lease 时等待后重新获取, 失去 Lease 时 hctx 被取消.
+		// 先于 Timer 检查, 避免未持有 Lease 时占用 Timer 的时间段
+		hctx := ctx
+		if lease != nil {
+			var held bool
+			if hctx, held = lease.acquire(ctx); !held {
+				r.KeepAlive()
+				wait = r.leaseInterval()
+				continue
+			}
+		}
+
+		// Kick 触发的执行不受 Timer 限制
+		if !kicked {
+			if d, gated := r.gate(time.Now()); gated {
+				wait = d
+				continue
+			}
+		}
+
+		done, overrun := r.handle(hctx)
+		skip = overrun && settings.skipNextOnOverrun
+		runs++
+		if done || (settings.maxRuns > 0 && runs >= settings.maxRuns) {
+			return nil
+		}
+
+		// Kick 触发的执行不占用 FixedRate 的时间点
+		if rate != nil && !kicked {
+			rate.done()
+		}
+		wait = r.delay(rate)
+	}
+}
+
+func (r *Runner) leaseInterval() time.Duration {
+	if interval := r.settings.Load().leaseInterval; interval > 0 {
+		return interval
+	}
+	if interval := r.Interval(); interval > 0 {
+		return interval
+	}
+	return time.Second
+}
+
+// sleep 等待 d 或被 Kick 唤醒, ctx 被取消时 ok 返回 false.
+// 等待期间 Interval 被调小时, 按新的 Interval 缩短等待
+func (r *Runner) sleep(ctx context.Context, d time.Duration, rate *rateClock) (kicked bool, ok bool) {
+	if d <= 0 {
 		select {
-		case <-r.stopCh:
-			return
-		case <-time.After(r.Interval):
-			r.handler.Handle()
-			r.KeepAlive()
+		case <-ctx.Done():
+			return false, false
+		default:
+			return false, true
+		}
+	}
+
+	start := time.Now()
+	deadline := start.Add(d)
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return false, false
+		case <-t.C:
+			return false, true
+		case <-r.kicker.ch:
+			return true, true
+		case <-r.reconfig:
+			now := time.Now()
+			next, ok := r.redelay(rate, start, now)
+			if !ok || !next.Before(deadline) {
+				continue
+			}
+			deadline = next
+
+			if !t.Stop() {
+				select {
+				case <-t.C:
+				default:
+				}
+			}
+			t.Reset(deadline.Sub(now))
+			r.metrics.SetNextDelay(deadline.Sub(now))
+		}
+	}
+}
+
+// redelay 在 Interval 修改后重新计算从 start 开始的等待的结束时间, Backoff 中的等待不受 Interval 影响
+func (r *Runner) redelay(rate *rateClock, start, now time.Time) (time.Time, bool) {
+	if r.ConsecutiveFailures() > 0 && r.settings.Load().backoff != nil {
+		return time.Time{}, false
+	}
+	if rate != nil {
+		return now.Add(rate.wait(now, r.Interval())), true
+	}
+	return start.Add(r.Interval()), true
+}
+
+// gate 使用 Timer 检查当前是否允许执行 Handle, 不允许时返回距离 Timer 给出的 next 的等待时间
+func (r *Runner) gate(now time.Time) (time.Duration, bool) {
+	t := r.settings.Load().timer
+	if t == nil {
+		return 0, false
+	}
+
+	is, next := t.ClockIn(now)
+	if is {
+		return 0, false
+	}
+
+	// next 无效时退化为等待 Interval, 避免空转
+	wait := next.Sub(now)
+	if next.IsZero() || wait <= 0 {
+		wait = r.Interval()
+	}
+	return wait, true
+}
+
+// handle 执行一次 HandleContext, 记录其结果并 keepalive. Handle 返回 ErrDone 时 done 为 true,
+// 执行超过 HandleTimeout 时 overrun 为 true
+func (r *Runner) handle(ctx context.Context) (done bool, overrun bool) {
+	// 本次 Handle 开始前的 KickAndWait 都以本次的结果返回, panic 时返回 ErrNotRunning
+	waiters := r.kicker.take()
+	result := ErrNotRunning
+	defer func() {
+		r.kicker.notify(waiters, result)
+	}()
+
+	timeout := r.settings.Load().handleTimeout
+	start := time.Now()
+	r.handleStart.Store(start.UnixNano())
+	err := func() error {
+		defer r.handleStart.Store(0)
+
+		if timeout > 0 {
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			return r.call(ctx)
+		}
+		return r.call(ctx)
+	}()
+	elapsed := time.Since(start)
+	if errors.Is(err, ErrDone) {
+		err, done = nil, true
+	}
+	var perr *PanicError
+	if errors.As(err, &perr) {
+		r.recovered(perr)
+	}
+
+	// Stop 或失去 Lease 引起的取消不视为失败, 也不保存 Checkpoint.
+	// ctx 被取消但 Handle 仍然完成(返回 nil 或其他 error)时, 与正常结束相同地记录
+	canceled := ctx.Err() != nil && errors.Is(err, context.Canceled)
+	if canceled {
+		err = nil
+	} else {
+		r.saveCheckpoint(func(cp *Checkpoint) {
+			cp.LastStart = start
+			if err != nil {
+				cp.LastError, cp.Error = time.Now(), err.Error()
+			} else {
+				cp.LastSuccess = time.Now()
+			}
+		})
+
+		failures := r.result.record(err)
+		if err != nil {
+			r.log(slog.LevelWarn, "runner handle failed", attemptAttr(failures), durationAttr(elapsed), errorAttr(err))
+			r.publish(TopicHandleFailed, &HandleFailedEvent{
+				Name:     r.name,
+				Time:     time.Now(),
+				Failures: failures,
+				Elapsed:  elapsed,
+				Err:      err,
+			})
 		}
 	}
+	r.metrics.ObserveHandle(elapsed, err)
+	r.KeepAlive()
+	if !canceled {
+		result = err
+		overrun = timeout > 0 && elapsed >= timeout
+	}
+	if overrun {
+		r.overrun(elapsed, err)
+	}
+	return done, overrun
+}
+
+// recovered 记录一次被 Recover 等 Middleware 恢复为 *PanicError 的 panic, Runner 会继续运行
+func (r *Runner) recovered(perr *PanicError) {
+	r.metrics.IncPanic()
+	r.log(slog.LevelError, "runner panic recovered", errorAttr(perr), slog.String("stack", string(perr.Stack)))
+	r.publish(TopicPanicked, &PanickedEvent{Name: r.name, Time: time.Now(), Panic: perr})
+}
+
+// restore 从 Checkpoints 中恢复上一次运行的 Checkpoint, 不存在时 ok 为 false
+func (r *Runner) restore() (cp Checkpoint, ok bool) {
+	r.checkpoint = Checkpoint{}
+	settings := r.settings.Load()
+	if settings.checkpoints == nil {
+		return Checkpoint{}, false
+	}
+
+	cp, ok, err := settings.checkpoints.Load(r.name)
+	if err != nil {
+		r.log(slog.LevelWarn, "runner checkpoint load failed", errorAttr(err))
+		return Checkpoint{}, false
+	}
+	if !ok || cp.LastStart.IsZero() {
+		return Checkpoint{}, false
+	}
+	r.checkpoint = cp
+
+	if t, ok := settings.timer.(interface{ ResetAs(tm time.Time) }); ok {
+		t.ResetAs(cp.LastStart)
+	}
+	return cp, true
+}
+
+// saveCheckpoint 使用 update 修改并保存 Checkpoint
+func (r *Runner) saveCheckpoint(update func(cp *Checkpoint)) {
+	store := r.settings.Load().checkpoints
+	if store == nil {
+		return
+	}
+
+	update(&r.checkpoint)
+	if err := store.Save(r.name, r.checkpoint); err != nil {
+		r.log(slog.LevelWarn, "runner checkpoint save failed", errorAttr(err))
+	}
+}
+
+// overrun 记录并回调一次 overrun
+func (r *Runner) overrun(elapsed time.Duration, err error) {
+	settings := r.settings.Load()
+	r.metrics.IncOverrun()
+	r.log(slog.LevelWarn, "runner handle overrun", durationAttr(elapsed), errorAttr(err), slog.Duration("timeout", settings.handleTimeout))
+
+	if settings.onOverrun != nil {
+		settings.onOverrun(Overrun{
+			Name:          r.name,
+			HandleTimeout: settings.handleTimeout,
+			Elapsed:       elapsed,
+			Err:           err,
+		})
+	}
+}
+
+// delay 计算并记录距离下一次 Handle 的等待时间
+func (r *Runner) delay(rate *rateClock) time.Duration {
+	d := r.nextDelay(rate)
+	r.metrics.SetNextDelay(d)
+	return d
+}
+
+// nextDelay 返回距离下一次 Handle 的等待时间, 连续失败时使用 Backoff
+func (r *Runner) nextDelay(rate *rateClock) time.Duration {
+	failures := r.ConsecutiveFailures()
+	if backoff := r.settings.Load().backoff; failures > 0 && backoff != nil {
+		d := backoff.Duration(failures)
+		r.log(slog.LevelInfo, "runner backoff", attemptAttr(failures), durationAttr(d))
+		return d
+	}
+	if rate != nil {
+		return rate.wait(time.Now(), r.Interval())
+	}
+	return r.Interval()
+}
+
+func (r *Runner) log(level slog.Level, msg string, attrs ...slog.Attr) {
+	logEvent(r.Slog(), level, msg, r.name, attrs...)
+}
+
+// runSettings 为 Start 时从 Runner 导出字段复制的配置, 运行中只读
+type runSettings struct {
+	backoff           *Backoff
+	timer             timer.Timer
+	fixedRate         *FixedRate
+	maxRuns           int
+	runUntil          time.Time
+	handleTimeout     time.Duration
+	onOverrun         func(overrun Overrun)
+	lease             Lease
+	leaseInterval     time.Duration
+	checkpoints       CheckpointStore
+	bus               eventbus.Bus
+	skipNextOnOverrun bool
+}
+
+// snapshot 复制当前的导出配置, Backoff 与 FixedRate 复制其值, 避免运行中修改指向的结构
+func (r *Runner) snapshot() *runSettings {
+	s := &runSettings{
+		timer:             r.Timer,
+		maxRuns:           r.MaxRuns,
+		runUntil:          r.RunUntil,
+		handleTimeout:     r.HandleTimeout,
+		onOverrun:         r.OnOverrun,
+		lease:             r.Lease,
+		leaseInterval:     r.LeaseInterval,
+		checkpoints:       r.Checkpoints,
+		bus:               r.Bus,
+		skipNextOnOverrun: r.SkipNextOnOverrun,
+	}
+	if r.Backoff != nil {
+		backoff := *r.Backoff
+		s.backoff = &backoff
+	}
+	if r.FixedRate != nil {
+		rate := *r.FixedRate
+		s.fixedRate = &rate
+	}
+	return s
+}
+
+// runnerConfig 记录 Runner 运行中可以修改的配置
+type runnerConfig struct {
+	mutex    sync.Mutex
+	interval time.Duration
+	timeout  time.Duration
+	logger   io.Writer
+	slog     *slog.Logger
+}
+
+func (c *runnerConfig) setLogger(logger io.Writer) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.logger = logger
+	c.slog = newTextLogger(logger)
+}
+
+func (c *runnerConfig) setSlog(logger *slog.Logger) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.logger = nil
+	c.slog = logger
+}
+
+func (c *runnerConfig) slogger() *slog.Logger {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.slog
+}
+
+// handleResult 记录 Handle 的执行结果
+type handleResult struct {
+	mutex    sync.Mutex
+	lastErr  error
+	failures int
+}
+
+// record 记录一次 Handle 的结果, 返回连续失败次数
+func (h *handleResult) record(err error) int {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if err == nil {
+		h.failures = 0
+		return 0
+	}
+
+	h.lastErr = err
+	h.failures++
+	return h.failures
 }
@@ -0,0 +1,387 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testHandler struct {
+	handled int32
+	handle  func(ctx context.Context) error
+}
+
+func (h *testHandler) HandleContext(ctx context.Context) error {
+	atomic.AddInt32(&h.handled, 1)
+	if h.handle != nil {
+		return h.handle(ctx)
+	}
+	return nil
+}
+
+func (h *testHandler) OnStart() error {
+	return nil
+}
+
+func (h *testHandler) OnExit() {
+}
+
+func (h *testHandler) count() int {
+	return int(atomic.LoadInt32(&h.handled))
+}
+
+func TestStopCancelsHandleContext(t *testing.T) {
+	req := require.New(t)
+
+	entered := make(chan struct{})
+	h := &testHandler{
+		handle: func(ctx context.Context) error {
+			close(entered)
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	}
+	r := NewContextRunner(h, "TestStopCancelsHandleContext", time.Hour)
+	req.NoError(r.Start())
+	<-entered
+
+	stopped := make(chan struct{})
+	go func() {
+		r.Stop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		req.Fail("Stop blocked by in-flight Handle")
+	}
+	req.Equal(1, h.count())
+}
+
+type plainHandler struct {
+	handled int32
+}
+
+func (h *plainHandler) Handle() {
+	atomic.AddInt32(&h.handled, 1)
+}
+
+func (h *plainHandler) OnStart() error {
+	return nil
+}
+
+func (h *plainHandler) OnExit() {
+}
+
+func TestAdaptHandler(t *testing.T) {
+	req := require.New(t)
+
+	h := &plainHandler{}
+	r := NewRunner(h, "TestAdaptHandler", time.Millisecond)
+	req.NoError(r.Start())
+	req.Eventually(func() bool {
+		return atomic.LoadInt32(&h.handled) >= 3
+	}, time.Second, time.Millisecond)
+	r.Stop()
+
+	// 已实现 ContextHandler 的 Handler 不会被包装
+	noop := &NoopHandler{}
+	req.Same(noop, AdaptHandler(noop))
+}
+
+func TestRestartAfterStop(t *testing.T) {
+	req := require.New(t)
+
+	h := &testHandler{}
+	r := NewContextRunner(h, "TestRestartAfterStop", time.Millisecond)
+	req.Equal(StateIdle, r.State())
+
+	for i := 0; i < 2; i++ {
+		req.NoError(r.Start())
+		req.Equal(StateRunning, r.State())
+		req.ErrorIs(r.Start(), ErrIsRunning)
+
+		before := h.count()
+		req.Eventually(func() bool {
+			return h.count() > before+2
+		}, time.Second, time.Millisecond)
+
+		r.Stop()
+		req.Equal(StateStopped, r.State())
+	}
+
+	expect := []State{
+		StateStarting, StateRunning, StateStopping, StateStopped,
+		StateStarting, StateRunning, StateStopping, StateStopped,
+	}
+	transitions := r.Transitions()
+	req.Len(transitions, len(expect))
+	for i, tr := range transitions {
+		req.Equal(expect[i], tr.To)
+	}
+}
+
+type exitOrderHandler struct {
+	testHandler
+	inHandle int32
+	exitSeen int32
+}
+
+func (h *exitOrderHandler) HandleContext(ctx context.Context) error {
+	atomic.StoreInt32(&h.inHandle, 1)
+	<-ctx.Done()
+	time.Sleep(10 * time.Millisecond)
+	atomic.StoreInt32(&h.inHandle, 0)
+	return nil
+}
+
+func (h *exitOrderHandler) OnExit() {
+	atomic.StoreInt32(&h.exitSeen, atomic.LoadInt32(&h.inHandle)+1)
+}
+
+func TestOnExitAfterLoopExit(t *testing.T) {
+	req := require.New(t)
+
+	h := &exitOrderHandler{}
+	r := NewContextRunner(h, "TestOnExitAfterLoopExit", time.Hour)
+	req.NoError(r.Start())
+	req.Eventually(func() bool {
+		return atomic.LoadInt32(&h.inHandle) == 1
+	}, time.Second, time.Millisecond)
+	r.Stop()
+
+	// exitSeen 为 1 表明 OnExit 回调时 Handle 已经返回
+	req.Equal(int32(1), atomic.LoadInt32(&h.exitSeen))
+}
+
+func TestStopContextAbandon(t *testing.T) {
+	req := require.New(t)
+
+	// 忽略 ctx 的 Handle
+	release := make(chan struct{})
+	h := &testHandler{
+		handle: func(ctx context.Context) error {
+			<-release
+			return nil
+		},
+	}
+	reg := NewRegistry()
+	r := NewContextRunner(h, "TestStopContextAbandon", time.Hour)
+	req.NoError(r.StartIn(reg))
+	req.Eventually(func() bool {
+		return h.count() == 1
+	}, time.Second, time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	req.ErrorIs(r.StopContext(ctx), context.DeadlineExceeded)
+	req.Equal(StateAbandoned, r.State())
+	req.Equal(0, reg.Len())
+	req.ErrorIs(r.Start(), ErrIsRunning)
+
+	// 阻塞的 Handle 不影响只读方法
+	readers := make(chan struct{})
+	go func() {
+		r.IsTimeout(time.Now())
+		r.Name()
+		r.Metrics().Snapshot()
+		close(readers)
+	}()
+	select {
+	case <-readers:
+	case <-time.After(time.Second):
+		req.Fail("readers blocked by hung Handle")
+	}
+
+	close(release)
+	<-r.Done()
+	req.Equal(StateStopped, r.State())
+}
+
+func TestSetInterval(t *testing.T) {
+	req := require.New(t)
+
+	h := &testHandler{}
+	r := NewContextRunner(h, "TestSetInterval", time.Hour)
+	req.NoError(r.StartIn(NewRegistry()))
+	defer r.Stop()
+	req.Eventually(func() bool {
+		return h.count() == 1
+	}, time.Second, time.Millisecond)
+
+	// 调小间隔时缩短正在进行的等待
+	r.SetInterval(time.Millisecond)
+	req.Equal(time.Millisecond, r.Interval())
+	req.Eventually(func() bool {
+		return h.count() > 3
+	}, time.Second, time.Millisecond)
+
+	// 运行中并发修改配置
+	var buf bytes.Buffer
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			r.SetInterval(time.Duration(i%3+1) * time.Millisecond)
+			r.SetTimeout(time.Minute)
+			r.SetLogger(&buf)
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		r.IsTimeout(time.Now())
+	}
+	<-done
+	req.Equal(time.Minute, r.Timeout())
+}
+
+func TestMaxRuns(t *testing.T) {
+	req := require.New(t)
+
+	h := &testHandler{}
+	reg := NewRegistry()
+	r := NewContextRunner(h, "TestMaxRuns", time.Millisecond)
+	r.MaxRuns = 3
+	req.NoError(r.StartIn(reg))
+
+	req.NoError(r.Wait())
+	req.Equal(3, h.count())
+	req.Equal(StateStopped, r.State())
+	req.Equal(0, reg.Len())
+
+	// 再次启动时重新计数
+	req.NoError(r.StartIn(reg))
+	req.NoError(r.Wait())
+	req.Equal(6, h.count())
+}
+
+func TestSettingsAtStart(t *testing.T) {
+	req := require.New(t)
+
+	h := &testHandler{}
+	reg := NewRegistry()
+	r := NewContextRunner(h, "TestSettingsAtStart", time.Millisecond, WithMaxRuns(3))
+	req.NoError(r.StartIn(reg))
+
+	// 运行中修改配置不影响本次运行, 在下一次 Start 时生效
+	r.MaxRuns = 1
+	r.Backoff = NewBackoff(time.Hour, time.Hour)
+	req.NoError(r.Wait())
+	req.Equal(3, h.count())
+
+	req.NoError(r.StartIn(reg))
+	req.NoError(r.Wait())
+	req.Equal(4, h.count())
+}
+
+func TestRunUntil(t *testing.T) {
+	req := require.New(t)
+
+	h := &testHandler{}
+	r := NewContextRunner(h, "TestRunUntil", time.Hour)
+	r.RunUntil = time.Now().Add(20 * time.Millisecond)
+	req.NoError(r.StartIn(NewRegistry()))
+
+	// 不等待完整的 Interval
+	select {
+	case <-r.Done():
+	case <-time.After(time.Second):
+		req.Fail("runner not finished at RunUntil")
+	}
+	req.NoError(r.Wait())
+	req.Equal(1, h.count())
+	req.Equal(StateStopped, r.State())
+}
+
+func TestHandleErrDone(t *testing.T) {
+	req := require.New(t)
+
+	h := &testHandler{}
+	h.handle = func(ctx context.Context) error {
+		if h.count() == 2 {
+			return fmt.Errorf("backfill finished: %w", ErrDone)
+		}
+		return nil
+	}
+	r := NewContextRunner(h, "TestHandleErrDone", time.Millisecond)
+	req.NoError(r.StartIn(NewRegistry()))
+
+	req.NoError(r.Wait())
+	req.Equal(2, h.count())
+	req.Nil(r.LastError())
+	req.Equal(StateStopped, r.State())
+}
+
+func TestHandleTimeout(t *testing.T) {
+	req := require.New(t)
+
+	overruns := make(chan Overrun, 10)
+	h := &testHandler{
+		handle: func(ctx context.Context) error {
+			_, ok := ctx.Deadline()
+			if !ok {
+				return errors.New("missing deadline")
+			}
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	}
+	r := NewContextRunner(h, "TestHandleTimeout", time.Hour)
+	r.HandleTimeout = 5 * time.Millisecond
+	r.OnOverrun = func(overrun Overrun) {
+		overruns <- overrun
+	}
+	req.NoError(r.StartIn(NewRegistry()))
+	defer r.Stop()
+
+	select {
+	case overrun := <-overruns:
+		req.Equal("TestHandleTimeout", overrun.Name)
+		req.Equal(5*time.Millisecond, overrun.HandleTimeout)
+		req.True(overrun.Elapsed >= 5*time.Millisecond)
+		req.ErrorIs(overrun.Err, context.DeadlineExceeded)
+	case <-time.After(time.Second):
+		req.Fail("overrun not reported")
+	}
+	req.EqualValues(1, r.Metrics().Snapshot().Overruns)
+	req.ErrorIs(r.LastError(), context.DeadlineExceeded)
+}
+
+func TestSkipNextOnOverrun(t *testing.T) {
+	req := require.New(t)
+
+	var starts []time.Time
+	var mutex sync.Mutex
+	h := &testHandler{}
+	h.handle = func(ctx context.Context) error {
+		mutex.Lock()
+		starts = append(starts, time.Now())
+		mutex.Unlock()
+
+		// 只有第一次 overrun
+		if h.count() == 1 {
+			time.Sleep(15 * time.Millisecond)
+		}
+		return nil
+	}
+	r := NewContextRunner(h, "TestSkipNextOnOverrun", 20*time.Millisecond)
+	r.HandleTimeout = 10 * time.Millisecond
+	r.SkipNextOnOverrun = true
+	r.MaxRuns = 2
+	req.NoError(r.StartIn(NewRegistry()))
+	req.NoError(r.Wait())
+
+	// overrun 后跳过一次, 第二次 Handle 在两个 Interval 之后
+	mutex.Lock()
+	defer mutex.Unlock()
+	req.Len(starts, 2)
+	req.True(starts[1].Sub(starts[0]) >= 15*time.Millisecond+40*time.Millisecond)
+	req.EqualValues(1, r.Metrics().Snapshot().Overruns)
+}
@@ -0,0 +1,49 @@
+package runner
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+)
+
+// goroutineID 返回当前 goroutine 的 id, 解析自 runtime.Stack 的首行 "goroutine N [running]:"
+func goroutineID() uint64 {
+	buf := make([]byte, 64)
+	buf = buf[:runtime.Stack(buf, false)]
+
+	buf = bytes.TrimPrefix(buf, []byte("goroutine "))
+	if i := bytes.IndexByte(buf, ' '); i > 0 {
+		buf = buf[:i]
+	}
+
+	id, err := strconv.ParseUint(string(buf), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return id
+}
+
+// goroutineStack 返回 id 对应 goroutine 的调用栈, goroutine 不存在时返回 nil
+func goroutineStack(id uint64) []byte {
+	if id == 0 {
+		return nil
+	}
+
+	buf := make([]byte, 64*1024)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+
+	prefix := []byte("goroutine " + strconv.FormatUint(id, 10) + " [")
+	for _, stack := range bytes.Split(buf, []byte("\n\n")) {
+		if bytes.HasPrefix(stack, prefix) {
+			return stack
+		}
+	}
+	return nil
+}
@@ -0,0 +1,200 @@
+package runner
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// State 为 Runner 生命周期中的状态
+//
+//	Idle ──> Starting ──> Running ──> Stopping ──> Stopped
+//	            │            │           │            ↑  │
+//	            └─> Failed <─┘           └> Abandoned ┘  │
+//	                  │                                  │
+//	                  └───────> Starting <───────────────┘
+//
+// 此外 Running 与 Paused 之间可以相互转换, Paused 与 Running 一样可以转换到 Stopping 与 Failed.
+// 循环正常结束(MaxRuns, RunUntil 或 ErrDone)时 Running 或 Paused 直接转换到 Stopped
+type State int32
+
+const (
+	// StateIdle 为创建后尚未启动
+	StateIdle State = iota
+	// StateStarting 为正在执行 OnStart
+	StateStarting
+	// StateRunning 为循环执行 Handle 中
+	StateRunning
+	// StateStopping 为正在等待循环退出
+	StateStopping
+	// StateStopped 为已停止或已正常结束, 可以再次 Start
+	StateStopped
+	// StateFailed 为 OnStart 失败或循环异常退出, 可以再次 Start
+	StateFailed
+	// StateAbandoned 为 StopContext 超时后放弃等待, 循环最终退出后进入 StateStopped
+	StateAbandoned
+	// StatePaused 为循环已暂停, 不再执行 Handle, 直到 Resume
+	StatePaused
+)
+
+var stateNames = map[State]string{
+	StateIdle:      "Idle",
+	StateStarting:  "Starting",
+	StateRunning:   "Running",
+	StateStopping:  "Stopping",
+	StateStopped:   "Stopped",
+	StateFailed:    "Failed",
+	StateAbandoned: "Abandoned",
+	StatePaused:    "Paused",
+}
+
+func (s State) String() string {
+	if name, ok := stateNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("State(%d)", int32(s))
+}
+
+// validTransitions 记录所有合法的状态转换, from : []to
+var validTransitions = map[State][]State{
+	StateIdle:      {StateStarting},
+	StateStarting:  {StateRunning, StateFailed},
+	StateRunning:   {StateStopping, StateStopped, StateFailed, StatePaused},
+	StatePaused:    {StateRunning, StateStopping, StateStopped, StateFailed},
+	StateStopping:  {StateStopped, StateAbandoned},
+	StateAbandoned: {StateStopped},
+	StateStopped:   {StateStarting},
+	StateFailed:    {StateStarting},
+}
+
+func canTransit(from, to State) bool {
+	for _, s := range validTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Transition 记录一次状态转换
+type Transition struct {
+	From State
+	To   State
+	At   time.Time
+
+	// Err 为转换到 StateFailed 的原因
+	Err error
+}
+
+// maxTransitionHistory 为保留的状态转换记录数量上限
+const maxTransitionHistory = 32
+
+// stateMachine 维护 Runner 的状态与有限长度的转换记录.
+// 其使用独立的锁, 查询状态时不会被 Start/Stop 阻塞
+type stateMachine struct {
+	mutex   sync.Mutex
+	state   State
+	history []Transition
+}
+
+// get 返回当前状态
+func (m *stateMachine) get() State {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.state
+}
+
+// transit 转换到 to 状态, 不合法的转换返回 error
+func (m *stateMachine) transit(to State, cause error) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	from := m.state
+	if !canTransit(from, to) {
+		return fmt.Errorf("invalid state transition %s -> %s", from, to)
+	}
+
+	m.state = to
+	m.history = append(m.history, Transition{
+		From: from,
+		To:   to,
+		At:   time.Now(),
+		Err:  cause,
+	})
+	if len(m.history) > maxTransitionHistory {
+		m.history = m.history[len(m.history)-maxTransitionHistory:]
+	}
+
+	return nil
+}
+
+// transitions 返回转换记录的拷贝, 按时间从旧到新排列
+func (m *stateMachine) transitions() []Transition {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	history := make([]Transition, len(m.history))
+	copy(history, m.history)
+	return history
+}
+
+// exitSignal 记录一次运行的退出, 用于实现 Done 与 Err
+type exitSignal struct {
+	mutex sync.Mutex
+	done  chan struct{}
+	err   error
+}
+
+// reset 在新一次运行开始前调用, 上一次运行已退出时创建新的 done
+func (e *exitSignal) reset() {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if e.done == nil || isClosed(e.done) {
+		e.done = make(chan struct{})
+	}
+	e.err = nil
+}
+
+// close 标记本次运行以 err 退出
+func (e *exitSignal) close(err error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if e.done == nil {
+		e.done = make(chan struct{})
+	}
+	if !isClosed(e.done) {
+		e.err = err
+		close(e.done)
+	}
+}
+
+// channel 返回本次运行退出时关闭的 channel
+func (e *exitSignal) channel() <-chan struct{} {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if e.done == nil {
+		e.done = make(chan struct{})
+	}
+	return e.done
+}
+
+// error 返回本次运行退出的原因
+func (e *exitSignal) error() error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return e.err
+}
+
+func isClosed(ch chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,413 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// RestartStrategy 决定某个 Runner 异常退出后 Supervisor 重启哪些 Runner
+type RestartStrategy int
+
+const (
+	// OneForOne 只重启异常退出的 Runner
+	OneForOne RestartStrategy = iota
+	// OneForAll 重启所有 Runner
+	OneForAll
+	// RestForOne 重启异常退出的 Runner 以及在其之后加入的 Runner
+	RestForOne
+)
+
+var (
+	// ErrTooManyRestarts 表示 Supervisor 在 Period 内的重启次数超过了 MaxRestarts
+	ErrTooManyRestarts = errors.New("too many restarts")
+)
+
+// Supervisor 管理一组 Supervisable, 在其异常退出(如 Handle panic)时按 RestartStrategy 进行重启.
+//
+// 每次重启前会等待指数增长的退避时间, 范围为 [MinBackoff, MaxBackoff].
+// Period 内重启次数超过 MaxRestarts 时, Supervisor 停止所有 Runner 并以 ErrTooManyRestarts 退出.
+// Supervisor 本身实现了 Supervisable, 因此可以被上层 Supervisor 管理.
+//
+// Note: 正常 Stop 的 Runner 不会被重启
+type Supervisor struct {
+	name     string
+	strategy RestartStrategy
+
+	MaxRestarts int
+	Period      time.Duration
+	MinBackoff  time.Duration
+	MaxBackoff  time.Duration
+	Logger      io.Writer
+
+	// Slog 不为 nil 时代替 Logger 输出结构化日志
+	Slog *slog.Logger
+
+	// Registry 为 Runner 启动与重启时使用的 Registry, Runner 未实现 StartIn 时使用 Start
+	Registry *Registry
+
+	// 流程控制相关
+	mutex  sync.Mutex
+	state  stateMachine
+	exit   exitSignal
+	ctx    context.Context
+	cancel context.CancelFunc
+	failCh chan failure
+	wg     sync.WaitGroup
+
+	// childMutex 保护 children 与 child.gen, monitor 只会持有 childMutex
+	childMutex sync.Mutex
+	children   []*child
+
+	// restarts 为 Period 内的重启时间, 只在 monitor 中访问
+	restarts []time.Time
+}
+
+// child 为 Supervisor 管理的 Runner, gen 在每次启动时递增, 用于丢弃过期的失败通知
+type child struct {
+	runner Supervisable
+	gen    int
+}
+
+type failure struct {
+	c   *child
+	gen int
+	err error
+}
+
+// NewSupervisor 创建一个使用 strategy 重启策略的 Supervisor
+func NewSupervisor(name string, strategy RestartStrategy) *Supervisor {
+	return &Supervisor{
+		name:        name,
+		strategy:    strategy,
+		MaxRestarts: 5,
+		Period:      time.Minute,
+		MinBackoff:  100 * time.Millisecond,
+		MaxBackoff:  10 * time.Second,
+		Registry:    DefaultRegistry,
+
+		state: stateMachine{state: StateIdle},
+	}
+}
+
+// Add 加入需要管理的 Runner, runner 需要实现 Supervisable. 启动顺序即为加入顺序, 停止顺序相反.
+// Supervisor 运行中时会立即启动加入的 Runner
+func (s *Supervisor) Add(runners ...IRunner) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, ir := range runners {
+		runner, ok := ir.(Supervisable)
+		if !ok {
+			return fmt.Errorf("runner %s does not implement Done and Err", ir.Name())
+		}
+		for _, c := range s.snapshot() {
+			if c.runner.Name() == runner.Name() {
+				return fmt.Errorf("conflict runner name %s", runner.Name())
+			}
+		}
+
+		c := &child{runner: runner}
+		running := s.state.get() == StateRunning
+		if running {
+			s.nextGen(c)
+			if err := s.startChild(c); err != nil {
+				return fmt.Errorf("start runner %s failed: %w", runner.Name(), err)
+			}
+		}
+
+		s.childMutex.Lock()
+		s.children = append(s.children, c)
+		s.childMutex.Unlock()
+
+		if running {
+			s.watch(s.ctx, s.failCh, c)
+		}
+	}
+
+	return nil
+}
+
+// Start 按加入顺序启动所有 Runner, 任一 Runner 启动失败时停止已启动的 Runner 并返回 error
+func (s *Supervisor) Start() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := s.state.transit(StateStarting, nil); err != nil {
+		return ErrIsRunning
+	}
+
+	// 等待上一次运行遗留的 goroutine 退出
+	s.wg.Wait()
+
+	children := s.snapshot()
+	for i, c := range children {
+		s.nextGen(c)
+		if err := s.startChild(c); err != nil {
+			stopReverse(children[:i])
+			err = fmt.Errorf("start runner %s failed: %w", c.runner.Name(), err)
+			_ = s.state.transit(StateFailed, err)
+			return err
+		}
+	}
+
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	s.failCh = make(chan failure)
+	s.restarts = nil
+	s.exit.reset()
+	_ = s.state.transit(StateRunning, nil)
+
+	s.wg.Add(1)
+	go s.monitor(s.ctx, s.failCh)
+	for _, c := range children {
+		s.watch(s.ctx, s.failCh, c)
+	}
+
+	return nil
+}
+
+// Stop 停止重启监控, 并按加入顺序的逆序停止所有 Runner
+func (s *Supervisor) Stop() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.state.transit(StateStopping, nil) != nil {
+		return
+	}
+
+	s.cancel()
+	s.wg.Wait()
+
+	stopReverse(s.snapshot())
+
+	_ = s.state.transit(StateStopped, nil)
+	s.exit.close(nil)
+}
+
+// KeepAlive 对所有 Runner 进行 keepalive
+func (s *Supervisor) KeepAlive() {
+	for _, c := range s.snapshot() {
+		c.runner.KeepAlive()
+	}
+}
+
+// IsTimeout 在任一 Runner 超时时返回 true
+func (s *Supervisor) IsTimeout(curTime time.Time) bool {
+	for _, c := range s.snapshot() {
+		if c.runner.IsTimeout(curTime) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Supervisor) Name() string {
+	return s.name
+}
+
+// State 返回 Supervisor 当前所处的状态
+func (s *Supervisor) State() State {
+	return s.state.get()
+}
+
+// Done 返回本次运行退出时关闭的 channel
+func (s *Supervisor) Done() <-chan struct{} {
+	return s.exit.channel()
+}
+
+// Err 返回本次运行退出的原因, 放弃重启时为 ErrTooManyRestarts
+func (s *Supervisor) Err() error {
+	return s.exit.error()
+}
+
+// watch 等待 c 本次运行退出, 异常退出时通知 monitor
+func (s *Supervisor) watch(ctx context.Context, failCh chan<- failure, c *child) {
+	s.childMutex.Lock()
+	gen := c.gen
+	s.childMutex.Unlock()
+	done := c.runner.Done()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-done:
+		}
+
+		err := c.runner.Err()
+		if err == nil {
+			return
+		}
+
+		select {
+		case failCh <- failure{c: c, gen: gen, err: err}:
+		case <-ctx.Done():
+		}
+	}()
+}
+
+// monitor 串行处理所有失败通知, 进行退避与重启
+func (s *Supervisor) monitor(ctx context.Context, failCh chan failure) {
+	defer s.wg.Done()
+
+	for {
+		var f failure
+		select {
+		case <-ctx.Done():
+			return
+		case f = <-failCh:
+		}
+
+		for s.isCurrent(f) {
+			s.log(slog.LevelWarn, "runner exited abnormally", f.c.runner.Name(), errorAttr(f.err))
+
+			if !s.allowRestart(time.Now()) {
+				s.giveUp(fmt.Errorf("%w {supervisor=%s}: %s", ErrTooManyRestarts, s.name, f.err))
+				return
+			}
+
+			wait := s.backoff()
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(wait):
+			}
+
+			next := s.restart(ctx, failCh, f.c, wait)
+			if next == nil {
+				break
+			}
+			f = *next
+		}
+	}
+}
+
+// restart 按重启策略重启 c 相关的 Runner, 某个 Runner 启动失败时返回对应的 failure.
+// wait 为重启前退避的时间
+func (s *Supervisor) restart(ctx context.Context, failCh chan<- failure, c *child, wait time.Duration) *failure {
+	s.childMutex.Lock()
+	var set []*child
+	switch s.strategy {
+	case OneForAll:
+		set = append(set, s.children...)
+	case RestForOne:
+		for i := range s.children {
+			if s.children[i] == c {
+				set = append(set, s.children[i:]...)
+				break
+			}
+		}
+	default:
+		set = append(set, c)
+	}
+	s.childMutex.Unlock()
+
+	stopReverse(set)
+	for _, sc := range set {
+		gen := s.nextGen(sc)
+		s.log(slog.LevelInfo, "runner restarted", sc.runner.Name(), attemptAttr(len(s.restarts)), durationAttr(wait))
+		if err := s.startChild(sc); err != nil {
+			return &failure{c: sc, gen: gen, err: err}
+		}
+		s.watch(ctx, failCh, sc)
+	}
+
+	return nil
+}
+
+// startChild 启动 c, 实现了 StartIn 时记录到 Registry 中
+func (s *Supervisor) startChild(c *child) error {
+	if r, ok := c.runner.(interface{ StartIn(reg *Registry) error }); ok && s.Registry != nil {
+		return r.StartIn(s.Registry)
+	}
+	return c.runner.Start()
+}
+
+// giveUp 停止所有 Runner, Supervisor 以 err 退出
+func (s *Supervisor) giveUp(err error) {
+	s.log(slog.LevelError, "supervisor give up", s.name, errorAttr(err))
+
+	s.cancel()
+	stopReverse(s.snapshot())
+
+	// Stop 正在进行时由 Stop 完成退出
+	if s.state.transit(StateFailed, err) == nil {
+		s.exit.close(err)
+	}
+}
+
+// allowRestart 检查 Period 内的重启次数, 允许时记录本次重启
+func (s *Supervisor) allowRestart(now time.Time) bool {
+	i := 0
+	for ; i < len(s.restarts); i++ {
+		if now.Sub(s.restarts[i]) <= s.Period {
+			break
+		}
+	}
+	s.restarts = s.restarts[i:]
+
+	if len(s.restarts) >= s.MaxRestarts {
+		return false
+	}
+	s.restarts = append(s.restarts, now)
+	return true
+}
+
+// backoff 返回本次重启前的等待时间, 随 Period 内的重启次数指数增长
+func (s *Supervisor) backoff() time.Duration {
+	d := s.MinBackoff
+	for i := 1; i < len(s.restarts) && d < s.MaxBackoff; i++ {
+		d *= 2
+	}
+	if d > s.MaxBackoff {
+		d = s.MaxBackoff
+	}
+	return d
+}
+
+func (s *Supervisor) isCurrent(f failure) bool {
+	s.childMutex.Lock()
+	defer s.childMutex.Unlock()
+
+	return f.c.gen == f.gen
+}
+
+func (s *Supervisor) nextGen(c *child) int {
+	s.childMutex.Lock()
+	defer s.childMutex.Unlock()
+
+	c.gen++
+	return c.gen
+}
+
+func (s *Supervisor) snapshot() []*child {
+	s.childMutex.Lock()
+	defer s.childMutex.Unlock()
+
+	children := make([]*child, len(s.children))
+	copy(children, s.children)
+	return children
+}
+
+// log 输出名为 name 的 Runner 的日志
+func (s *Supervisor) log(level slog.Level, msg, name string, attrs ...slog.Attr) {
+	logger := s.Slog
+	if logger == nil {
+		logger = newTextLogger(s.Logger)
+	}
+	logEvent(logger, level, msg, name, append(attrs, slog.String("supervisor", s.name))...)
+}
+
+// stopReverse 按逆序停止 children
+func stopReverse(children []*child) {
+	for i := len(children) - 1; i >= 0; i-- {
+		children[i].runner.Stop()
+	}
+}
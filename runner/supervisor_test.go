@@ -0,0 +1,158 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// panicTimes 返回一个前 n 次调用 panic 的 handle
+func panicTimes(n int32) func(ctx context.Context) error {
+	var calls int32
+	return func(ctx context.Context) error {
+		if atomic.AddInt32(&calls, 1) <= n {
+			panic("boom")
+		}
+		return nil
+	}
+}
+
+func newTestSupervisor(name string, strategy RestartStrategy) *Supervisor {
+	s := NewSupervisor(name, strategy)
+	s.MinBackoff = time.Millisecond
+	s.MaxBackoff = 5 * time.Millisecond
+	return s
+}
+
+func TestSupervisorOneForOne(t *testing.T) {
+	req := require.New(t)
+
+	bad := &testHandler{handle: panicTimes(2)}
+	good := &testHandler{}
+	badRunner := NewContextRunner(bad, "TestSupervisorOneForOne-bad", time.Millisecond)
+	goodRunner := NewContextRunner(good, "TestSupervisorOneForOne-good", time.Hour)
+
+	s := newTestSupervisor("TestSupervisorOneForOne", OneForOne)
+	req.NoError(s.Add(goodRunner, badRunner))
+	req.NoError(s.Start())
+	defer s.Stop()
+
+	req.Eventually(func() bool {
+		return bad.count() > 3 && badRunner.State() == StateRunning
+	}, time.Second, time.Millisecond)
+
+	// 未失败的 Runner 不会被重启
+	req.Equal(1, good.count())
+	req.Equal(StateRunning, s.State())
+}
+
+func TestSupervisorOneForAll(t *testing.T) {
+	req := require.New(t)
+
+	bad := &testHandler{handle: panicTimes(1)}
+	good := &testHandler{}
+	s := newTestSupervisor("TestSupervisorOneForAll", OneForAll)
+	req.NoError(s.Add(
+		NewContextRunner(good, "TestSupervisorOneForAll-good", time.Hour),
+		NewContextRunner(bad, "TestSupervisorOneForAll-bad", time.Hour),
+	))
+	req.NoError(s.Start())
+	defer s.Stop()
+
+	// good 随 bad 一起被重启, 因此会再次执行 do while 的第一次 Handle
+	req.Eventually(func() bool {
+		return good.count() == 2 && bad.count() == 2
+	}, time.Second, time.Millisecond)
+}
+
+func TestSupervisorTooManyRestarts(t *testing.T) {
+	req := require.New(t)
+
+	bad := &testHandler{handle: panicTimes(100)}
+	badRunner := NewContextRunner(bad, "TestSupervisorTooManyRestarts", time.Millisecond)
+	s := newTestSupervisor("TestSupervisorTooManyRestarts", OneForOne)
+	s.MaxRestarts = 3
+	req.NoError(s.Add(badRunner))
+	req.NoError(s.Start())
+
+	select {
+	case <-s.Done():
+	case <-time.After(time.Second):
+		req.Fail("supervisor did not give up")
+	}
+	req.True(errors.Is(s.Err(), ErrTooManyRestarts))
+	req.Equal(StateFailed, s.State())
+	req.Equal(4, bad.count())
+
+	var perr *PanicError
+	req.ErrorAs(badRunner.Err(), &perr)
+}
+
+func TestSupervisorRegistry(t *testing.T) {
+	req := require.New(t)
+
+	reg := NewRegistry()
+	bad := &testHandler{handle: panicTimes(1)}
+	s := newTestSupervisor("TestSupervisorRegistry", OneForOne)
+	s.Registry = reg
+	req.NoError(s.Add(NewContextRunner(bad, "TestSupervisorRegistry-bad", time.Hour)))
+	req.NoError(s.Start())
+	defer s.Stop()
+
+	// 重启后的 Runner 与运行中加入的 Runner 同样记录到 Registry 中
+	req.Eventually(func() bool {
+		return bad.count() == 2
+	}, time.Second, time.Millisecond)
+	_, ok := reg.Lookup("TestSupervisorRegistry-bad")
+	req.True(ok)
+
+	req.NoError(s.Add(NewContextRunner(&testHandler{}, "TestSupervisorRegistry-good", time.Hour)))
+	req.Equal(2, reg.Len())
+	_, ok = DefaultRegistry.Lookup("TestSupervisorRegistry-good")
+	req.False(ok)
+
+	s.Stop()
+	req.Equal(0, reg.Len())
+}
+
+// plainRunner 只实现了 IRunner
+type plainRunner struct {
+	name string
+}
+
+func (r *plainRunner) Start() error {
+	return nil
+}
+
+func (r *plainRunner) Stop() {
+}
+
+func (r *plainRunner) KeepAlive() {
+}
+
+func (r *plainRunner) IsTimeout(curTime time.Time) bool {
+	return false
+}
+
+func (r *plainRunner) Name() string {
+	return r.name
+}
+
+func TestSupervisorRequiresSupervisable(t *testing.T) {
+	req := require.New(t)
+
+	// 只实现 IRunner 的 Runner 仍可以加入 Group 与 Registry, 但无法被 Supervisor 管理
+	var _ IRunner = &plainRunner{}
+	var _ Supervisable = &Runner{}
+	var _ Supervisable = &Pool{}
+	var _ Supervisable = &Supervisor{}
+
+	s := newTestSupervisor("TestSupervisorRequiresSupervisable", OneForOne)
+	err := s.Add(&plainRunner{name: "plain"})
+	req.Error(err)
+	req.Contains(err.Error(), "does not implement Done and Err")
+}
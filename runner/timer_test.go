@@ -0,0 +1,84 @@
+package runner
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/KanShiori/kit/timer"
+)
+
+// openAtTimer 在 openAt 之前不允许执行, 并记录 ClockIn 的调用次数
+type openAtTimer struct {
+	mutex  sync.Mutex
+	openAt time.Time
+	clocks int
+}
+
+func (t *openAtTimer) Clock() (bool, time.Time) {
+	return t.ClockIn(time.Now())
+}
+
+func (t *openAtTimer) ClockIn(tm time.Time) (bool, time.Time) {
+	t.mutex.Lock()
+	t.clocks++
+	t.mutex.Unlock()
+	return t.IsTimeUp(tm)
+}
+
+func (t *openAtTimer) IsTimeUpNow() (bool, time.Time) {
+	return t.IsTimeUp(time.Now())
+}
+
+func (t *openAtTimer) IsTimeUp(tm time.Time) (bool, time.Time) {
+	if tm.Before(t.openAt) {
+		return false, t.openAt
+	}
+	return true, tm
+}
+
+func (t *openAtTimer) clockCount() int {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.clocks
+}
+
+var _ timer.Timer = &openAtTimer{}
+
+func TestTimerGatedRunner(t *testing.T) {
+	req := require.New(t)
+
+	gate := &openAtTimer{openAt: time.Now().Add(50 * time.Millisecond)}
+	h := &testHandler{}
+	r := NewContextRunner(h, "TestTimerGatedRunner", time.Hour)
+	r.Timer = gate
+	req.NoError(r.StartIn(NewRegistry()))
+	defer r.Stop()
+
+	time.Sleep(20 * time.Millisecond)
+	req.Equal(0, h.count())
+
+	req.Eventually(func() bool {
+		return h.count() == 1
+	}, time.Second, time.Millisecond)
+
+	// 等待 next 而不是轮询: 一次拒绝与一次允许
+	req.Equal(2, gate.clockCount())
+}
+
+func TestTimerSpanRunner(t *testing.T) {
+	req := require.New(t)
+
+	h := &testHandler{}
+	r := NewContextRunner(h, "TestTimerSpanRunner", time.Millisecond)
+	r.Timer = timer.NewTimeSpan(30*time.Millisecond, time.Time{})
+	req.NoError(r.StartIn(NewRegistry()))
+	defer r.Stop()
+
+	// Interval 为 1ms, 但 TimeSpan 限制每 30ms 最多执行一次
+	time.Sleep(100 * time.Millisecond)
+	n := h.count()
+	req.True(n >= 2 && n <= 5, "handled %d times", n)
+}
@@ -0,0 +1,318 @@
+package runner
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// TimeoutReport 为 Watchdog 检测到的一次 Runner 超时
+type TimeoutReport struct {
+	// Name 为 Runner 在 Registry 中的名称
+	Name     string
+	Runner   IRunner
+	Registry *Registry
+
+	// Time 为检测到超时的时间
+	Time time.Time
+
+	// HandleElapsed 为执行中的 Handle 已经执行的时间, 为 0 表示阻塞不在 Handle 中
+	HandleElapsed time.Duration
+
+	// Stack 为 Runner 循环所在 goroutine 的调用栈, 未开启 Watchdog.CaptureStack 时为 nil
+	Stack []byte
+}
+
+// WatchdogAction 为 Watchdog 检测到 Runner 超时后执行的动作
+type WatchdogAction func(report TimeoutReport)
+
+// LogAction 将超时信息以 slog.TextHandler 的格式写入 w
+func LogAction(w io.Writer) WatchdogAction {
+	return SlogAction(newTextLogger(w))
+}
+
+// SlogAction 将超时信息输出到 logger, duration 为执行中的 Handle 已经执行的时间
+func SlogAction(logger *slog.Logger) WatchdogAction {
+	return func(report TimeoutReport) {
+		attrs := []slog.Attr{durationAttr(report.HandleElapsed)}
+		if report.Stack != nil {
+			attrs = append(attrs, slog.String("stack", string(report.Stack)))
+		}
+		logEvent(logger, slog.LevelWarn, "runner timeout", report.Name, attrs...)
+	}
+}
+
+// watchdogStopTimeout 为 StopAction 与 RestartAction 等待 Runner 停止的时间上限
+const watchdogStopTimeout = 30 * time.Second
+
+// StopAction 停止超时的 Runner. 同一个 Runner 的停止未结束前不会重复停止,
+// 实现了 StopContext 的 Runner 最多等待 30 秒, 之后进入 StateAbandoned
+func StopAction() WatchdogAction {
+	guard := newStopGuard()
+	return func(report TimeoutReport) {
+		if !guard.begin(report.Name) {
+			return
+		}
+
+		// Stop 会等待阻塞的 Handle 返回, 因此不在 Watchdog 的循环中执行
+		go func() {
+			defer guard.end(report.Name)
+			_ = stopWithTimeout(report.Runner)
+		}()
+	}
+}
+
+// RestartAction 停止超时的 Runner, 并重新启动到原来的 Registry 中.
+// 与 StopAction 相同, 停止未结束前不会重复停止, 停止超时时不重新启动
+func RestartAction() WatchdogAction {
+	guard := newStopGuard()
+	return func(report TimeoutReport) {
+		if !guard.begin(report.Name) {
+			return
+		}
+
+		go func() {
+			defer guard.end(report.Name)
+			if err := stopWithTimeout(report.Runner); err != nil {
+				return
+			}
+
+			if r, ok := report.Runner.(interface{ StartIn(reg *Registry) error }); ok && report.Registry != nil {
+				_ = r.StartIn(report.Registry)
+				return
+			}
+			_ = report.Runner.Start()
+		}()
+	}
+}
+
+// stopWithTimeout 停止 r, 实现了 StopContext 的 Runner 最多等待 watchdogStopTimeout
+func stopWithTimeout(r IRunner) error {
+	s, ok := r.(interface {
+		StopContext(ctx context.Context) error
+	})
+	if !ok {
+		r.Stop()
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), watchdogStopTimeout)
+	defer cancel()
+	return s.StopContext(ctx)
+}
+
+// stopGuard 记录正在被停止的 Runner 的名称, 避免每次检查到超时都启动新的停止
+type stopGuard struct {
+	mutex    sync.Mutex
+	stopping map[string]struct{}
+}
+
+func newStopGuard() *stopGuard {
+	return &stopGuard{stopping: make(map[string]struct{})}
+}
+
+// begin 开始停止名为 name 的 Runner, 已在停止中时返回 false
+func (g *stopGuard) begin(name string) bool {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if _, ok := g.stopping[name]; ok {
+		return false
+	}
+	g.stopping[name] = struct{}{}
+	return true
+}
+
+func (g *stopGuard) end(name string) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	delete(g.stopping, name)
+}
+
+// Watchdog 周期性检查 Registry 中所有 Runner 是否超时, 并对超时的 Runner 执行 WatchdogAction.
+//
+// 默认每次检查到超时都会执行动作. Grace 为 true 时, 一次阻塞只在第一次检查到时执行动作,
+// 直到 Runner 恢复(不再超时)后重新计算.
+type Watchdog struct {
+	registry *Registry
+	interval time.Duration
+	actions  []WatchdogAction
+
+	// Grace 为 true 时每次阻塞只执行一次动作, 需在 Start 前设置
+	Grace bool
+
+	// CaptureStack 为 true 时在 TimeoutReport 中附带阻塞 goroutine 的调用栈, 需在 Start 前设置.
+	// 获取调用栈需要 stop the world, 默认开启
+	CaptureStack bool
+
+	mutex    sync.Mutex
+	timeouts map[string]time.Duration // name : timeout
+
+	checkMutex sync.Mutex
+	stalled    map[string]IRunner // 已执行过动作的阻塞 Runner
+
+	// 流程控制相关
+	running bool
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// NewWatchdog 创建一个每隔 interval 检查 registry 的 Watchdog
+func NewWatchdog(registry *Registry, interval time.Duration, actions ...WatchdogAction) *Watchdog {
+	return &Watchdog{
+		registry: registry,
+		interval: interval,
+		actions:  actions,
+
+		CaptureStack: true,
+
+		timeouts: make(map[string]time.Duration),
+		stalled:  make(map[string]IRunner),
+	}
+}
+
+// SetTimeout 覆盖名为 name 的 Runner 的超时时间, timeout 为 0 时取消覆盖.
+// 覆盖只对实现了 IsTimeoutAfter 或 LastHandleTime 的 Runner 生效, 其他 Runner 仍使用 IsTimeout
+func (w *Watchdog) SetTimeout(name string, timeout time.Duration) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if timeout <= 0 {
+		delete(w.timeouts, name)
+		return
+	}
+	w.timeouts[name] = timeout
+}
+
+// Start 在后台开始周期性检查
+func (w *Watchdog) Start() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.running {
+		return ErrIsRunning
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w.cancel = cancel
+	w.running = true
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		w.Run(ctx)
+	}()
+
+	return nil
+}
+
+// Stop 停止检查并等待其退出
+func (w *Watchdog) Stop() {
+	w.mutex.Lock()
+	if !w.running {
+		w.mutex.Unlock()
+		return
+	}
+	w.running = false
+	w.cancel()
+	w.mutex.Unlock()
+
+	w.wg.Wait()
+}
+
+// Run 周期性检查直到 ctx 被取消
+func (w *Watchdog) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case curTime := <-ticker.C:
+			w.Check(curTime)
+		}
+	}
+}
+
+// Check 检查一次所有 Runner, 对超时的 Runner 执行动作
+func (w *Watchdog) Check(curTime time.Time) {
+	w.checkMutex.Lock()
+	defer w.checkMutex.Unlock()
+
+	seen := make(map[string]bool)
+
+	w.registry.Range(func(name string, runner IRunner) bool {
+		seen[name] = true
+
+		if !w.isTimeout(name, runner, curTime) {
+			delete(w.stalled, name)
+			return true
+		}
+
+		if w.Grace {
+			if w.stalled[name] == runner {
+				return true
+			}
+			w.stalled[name] = runner
+		}
+
+		report := TimeoutReport{
+			Name:     name,
+			Runner:   runner,
+			Registry: w.registry.scopeOf(name, runner),
+			Time:     curTime,
+		}
+		if r, ok := runner.(interface{ Metrics() *Metrics }); ok {
+			r.Metrics().IncTimeout()
+		}
+		if r, ok := runner.(interface{ HandleElapsed(time.Time) time.Duration }); ok {
+			report.HandleElapsed = r.HandleElapsed(curTime)
+		}
+		if r, ok := runner.(interface{ Stack() []byte }); ok && w.CaptureStack {
+			report.Stack = r.Stack()
+		}
+
+		// Runner 自身输出超时日志, 并在设置了 Bus 时发布超时事件
+		if r, ok := runner.(*Runner); ok {
+			r.log(slog.LevelWarn, "runner timeout", durationAttr(report.HandleElapsed))
+			r.publish(TopicTimedOut, newTimedOutEvent(report))
+		}
+		for _, action := range w.actions {
+			action(report)
+		}
+
+		return true
+	})
+
+	// 清理已不在 Registry 中的 Runner
+	for name := range w.stalled {
+		if !seen[name] {
+			delete(w.stalled, name)
+		}
+	}
+}
+
+func (w *Watchdog) isTimeout(name string, runner IRunner, curTime time.Time) bool {
+	w.mutex.Lock()
+	timeout, ok := w.timeouts[name]
+	w.mutex.Unlock()
+
+	if !ok {
+		return runner.IsTimeout(curTime)
+	}
+
+	// 优先使用 IsTimeoutAfter, 其会考虑暂停等 Runner 自身的状态
+	if r, ok := runner.(interface {
+		IsTimeoutAfter(curTime time.Time, timeout time.Duration) bool
+	}); ok {
+		return r.IsTimeoutAfter(curTime, timeout)
+	}
+	if r, ok := runner.(interface{ LastHandleTime() time.Time }); ok {
+		return curTime.Sub(r.LastHandleTime()) > timeout
+	}
+	return runner.IsTimeout(curTime)
+}
@@ -0,0 +1,175 @@
+package runner
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// blockingHandler 的 Handle 会阻塞直到 release 被关闭或 ctx 被取消
+type blockingHandler struct {
+	testHandler
+	release chan struct{}
+}
+
+func newBlockingHandler() *blockingHandler {
+	h := &blockingHandler{release: make(chan struct{})}
+	h.handle = func(ctx context.Context) error {
+		select {
+		case <-h.release:
+		case <-ctx.Done():
+		}
+		return nil
+	}
+	return h
+}
+
+func TestWatchdogGrace(t *testing.T) {
+	req := require.New(t)
+
+	reg := NewRegistry()
+	h := newBlockingHandler()
+	r := NewContextRunner(h, "stalled", time.Hour)
+	r.SetTimeout(time.Minute)
+	req.NoError(r.StartIn(reg.Namespace("ns")))
+	defer r.Stop()
+	req.Eventually(func() bool {
+		return r.HandleElapsed(time.Now()) > 0
+	}, time.Second, time.Millisecond)
+
+	var fired int32
+	var reports []TimeoutReport
+	w := NewWatchdog(reg, time.Hour, func(report TimeoutReport) {
+		atomic.AddInt32(&fired, 1)
+		reports = append(reports, report)
+	})
+	w.Grace = true
+
+	now := time.Now()
+	w.Check(now)
+	req.Equal(int32(0), atomic.LoadInt32(&fired))
+
+	// 同一次阻塞只执行一次动作
+	w.Check(now.Add(2 * time.Minute))
+	w.Check(now.Add(3 * time.Minute))
+	req.Equal(int32(1), atomic.LoadInt32(&fired))
+	req.Equal("ns/stalled", reports[0].Name)
+	req.True(reports[0].HandleElapsed > 0)
+	req.Contains(string(reports[0].Stack), "newBlockingHandler")
+	found, ok := reports[0].Registry.Lookup("stalled")
+	req.True(ok)
+	req.Same(r, found)
+
+	// 恢复后再次阻塞会重新执行动作
+	r.KeepAlive()
+	w.Check(time.Now())
+	w.Check(time.Now().Add(2 * time.Minute))
+	req.Equal(int32(2), atomic.LoadInt32(&fired))
+}
+
+func TestWatchdogTimeoutOverrideAndStop(t *testing.T) {
+	req := require.New(t)
+
+	reg := NewRegistry()
+	h := newBlockingHandler()
+	r := NewContextRunner(h, "TestWatchdogTimeoutOverrideAndStop", time.Hour)
+	req.NoError(r.StartIn(reg))
+
+	w := NewWatchdog(reg, time.Millisecond, StopAction())
+	w.SetTimeout(r.Name(), 5*time.Millisecond)
+	req.NoError(w.Start())
+	defer w.Stop()
+
+	req.Eventually(func() bool {
+		return r.State() == StateStopped
+	}, time.Second, time.Millisecond)
+	req.Equal(0, reg.Len())
+}
+
+func TestWatchdogTimeoutOverridePaused(t *testing.T) {
+	req := require.New(t)
+
+	reg := NewRegistry()
+	r := NewContextRunner(&testHandler{}, "TestWatchdogTimeoutOverridePaused", time.Hour)
+	req.NoError(r.StartIn(reg))
+	defer r.Stop()
+	r.Pause()
+
+	var fired int32
+	w := NewWatchdog(reg, time.Hour, func(report TimeoutReport) {
+		atomic.AddInt32(&fired, 1)
+	})
+	w.SetTimeout(r.Name(), time.Millisecond)
+
+	// 暂停期间单独设置的超时也不生效
+	w.Check(time.Now().Add(time.Minute))
+	req.Equal(int32(0), atomic.LoadInt32(&fired))
+
+	r.Resume()
+	w.Check(time.Now().Add(time.Minute))
+	req.Equal(int32(1), atomic.LoadInt32(&fired))
+}
+
+// stuckRunner 的 StopContext 阻塞到 release 关闭, 并记录调用次数
+type stuckRunner struct {
+	plainRunner
+	stops   int32
+	release chan struct{}
+}
+
+func (r *stuckRunner) StopContext(ctx context.Context) error {
+	atomic.AddInt32(&r.stops, 1)
+	select {
+	case <-r.release:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func TestStopActionNoRepeat(t *testing.T) {
+	req := require.New(t)
+
+	r := &stuckRunner{plainRunner: plainRunner{name: "stuck"}, release: make(chan struct{})}
+	report := TimeoutReport{Name: r.Name(), Runner: r, Time: time.Now()}
+
+	// 停止未结束前每次检查到超时都不会启动新的停止
+	action := StopAction()
+	for i := 0; i < 5; i++ {
+		action(report)
+	}
+	req.Eventually(func() bool {
+		return atomic.LoadInt32(&r.stops) == 1
+	}, time.Second, time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	req.Equal(int32(1), atomic.LoadInt32(&r.stops))
+
+	// 停止结束后可以再次停止
+	close(r.release)
+	req.Eventually(func() bool {
+		action(report)
+		return atomic.LoadInt32(&r.stops) >= 2
+	}, time.Second, time.Millisecond)
+}
+
+func TestGoroutineStack(t *testing.T) {
+	req := require.New(t)
+
+	req.NotZero(goroutineID())
+	req.True(strings.HasPrefix(string(goroutineStack(goroutineID())), "goroutine "))
+	req.Nil(goroutineStack(0))
+
+	r := NewContextRunner(newBlockingHandler(), "TestGoroutineStack", time.Hour)
+	req.Nil(r.Stack())
+	req.NoError(r.StartIn(NewRegistry()))
+	req.Eventually(func() bool {
+		return strings.Contains(string(r.Stack()), "newBlockingHandler")
+	}, time.Second, time.Millisecond)
+	r.Stop()
+	req.Nil(r.Stack())
+	req.Zero(r.HandleElapsed(time.Now()))
+}
@@ -1,7 +1,6 @@
 package timer
 
 import (
-	"fmt"
 	"time"
 )
 
@@ -10,10 +9,11 @@ type TimeSpan struct {
 	lastAt   time.Time
 }
 
+// NewTimeSpan 创建间隔为 interval 的 TimeSpan, lastAt 为上一次 timeup 的时间, 零值表示立即 timeup
 func NewTimeSpan(interval time.Duration, lastAt time.Time) *TimeSpan {
 	return &TimeSpan{
 		interval: interval,
-		lastAt:   time.Time{},
+		lastAt:   lastAt,
 	}
 }
 
@@ -40,7 +40,6 @@ func (t *TimeSpan) IsTimeUpNow() (bool, time.Time) {
 // IsTimeUp 与 ts 比较
 func (t *TimeSpan) IsTimeUp(tm time.Time) (bool, time.Time) {
 	next := t.lastAt.Add(t.interval)
-	fmt.Println(next, tm)
 	if tm.Before(next) {
 		return false, next
 	}
@@ -76,3 +76,12 @@ func (t *TimerList) IsTimeUp(tm time.Time) (bool, time.Time) {
 	}
 	return true, tm
 }
+
+// ResetAs 对其中实现了 ResetAs 的 Timer(如 TimeSpan) 置 last 为 tm
+func (t *TimerList) ResetAs(tm time.Time) {
+	for _, timer := range t.timers {
+		if r, ok := timer.(interface{ ResetAs(tm time.Time) }); ok {
+			r.ResetAs(tm)
+		}
+	}
+}
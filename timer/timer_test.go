@@ -53,3 +53,16 @@ func TestTimerList(t *testing.T) {
 	is, next = timer.ClockIn(tm)
 	t.Logf("is:%v next:%s\n", is, next)
 }
+
+func TestNewTimeSpanLastAt(t *testing.T) {
+	lastAt := time.Now().Add(-30 * time.Minute)
+	ts := NewTimeSpan(time.Hour, lastAt)
+
+	is, next := ts.IsTimeUp(time.Now())
+	if is {
+		t.Fatal("time up before interval since lastAt")
+	}
+	if !next.Equal(lastAt.Add(time.Hour)) {
+		t.Fatalf("unexpected next %s", next)
+	}
+}